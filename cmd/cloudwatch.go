@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+type metricDimension struct {
+	name  string
+	value string
+}
+
+func (app *applicationMain) createCloudWatchClient() (*cloudwatch.Client, error) {
+	cfg, err := app.awsConfig(context.Background(), app.Region)
+	if err != nil {
+		return nil, err
+	}
+	return cloudwatch.NewFromConfig(cfg), nil
+}
+
+func (app *applicationMain) lambdaMetricSum(ctx context.Context, metric string, dimensions []metricDimension, start time.Time, end time.Time) (float64, error) {
+	client, err := app.createCloudWatchClient()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create CloudWatch connection:\n%v", err)
+	}
+
+	var cwDimensions []cwtypes.Dimension
+	for _, d := range dimensions {
+		cwDimensions = append(cwDimensions, cwtypes.Dimension{Name: aws.String(d.name), Value: aws.String(d.value)})
+	}
+	out, err := client.GetMetricStatistics(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/Lambda"),
+		MetricName: aws.String(metric),
+		Dimensions: cwDimensions,
+		StartTime:  aws.Time(start),
+		EndTime:    aws.Time(end),
+		Period:     aws.Int32(60),
+		Statistics: []cwtypes.Statistic{cwtypes.StatisticSum},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s metric:\n%v", metric, err)
+	}
+
+	sum := 0.0
+	for _, point := range out.Datapoints {
+		sum += aws.ToFloat64(point.Sum)
+	}
+	return sum, nil
+}
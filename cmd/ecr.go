@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const ecrDefaultPartSize = 20 * 1024 * 1024
+
+var ecrManifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+// ecrImage is an image reference of the form <account>.dkr.ecr.<region>.amazonaws.com/<repo>[:tag|@digest]
+type ecrImage struct {
+	account    string
+	region     string
+	repository string
+	tag        string
+	digest     string
+}
+
+func parseEcrImage(uri string) (ecrImage, error) {
+	var img ecrImage
+	host, path, found := strings.Cut(uri, "/")
+	hostParts := strings.Split(host, ".")
+	if !found || len(hostParts) < 6 || hostParts[1] != "dkr" || hostParts[2] != "ecr" {
+		return img, fmt.Errorf("%s is not an ECR image uri", uri)
+	}
+	img.account = hostParts[0]
+	img.region = hostParts[3]
+
+	if repo, digest, ok := strings.Cut(path, "@"); ok {
+		img.repository, img.digest = repo, digest
+	} else if idx := strings.LastIndex(path, ":"); idx > 0 {
+		img.repository, img.tag = path[:idx], path[idx+1:]
+	} else {
+		img.repository = path
+	}
+	return img, nil
+}
+
+func (i ecrImage) repositoryUri() string {
+	return fmt.Sprintf("%s.dkr.ecr.%s.amazonaws.com/%s", i.account, i.region, i.repository)
+}
+
+func (i ecrImage) imageId() ecrtypes.ImageIdentifier {
+	if i.digest != "" {
+		return ecrtypes.ImageIdentifier{ImageDigest: aws.String(i.digest)}
+	}
+	return ecrtypes.ImageIdentifier{ImageTag: aws.String(i.tag)}
+}
+
+type imageManifest struct {
+	Config *struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Layers []struct {
+		Digest string `json:"digest"`
+	} `json:"layers"`
+	Manifests []struct {
+		Digest string `json:"digest"`
+	} `json:"manifests"`
+}
+
+func (app *applicationMain) createEcrClient(region string) (*ecr.Client, error) {
+	cfg, err := app.awsConfig(context.Background(), region)
+	if err != nil {
+		return nil, err
+	}
+	return ecr.NewFromConfig(cfg), nil
+}
+
+func (app *applicationMain) copyEcrImage(ctx context.Context, sourceUri string, targetRepositoryUri string) (string, error) {
+	src, err := parseEcrImage(sourceUri)
+	if err != nil {
+		return "", err
+	}
+	dst, err := parseEcrImage(targetRepositoryUri)
+	if err != nil {
+		return "", err
+	}
+
+	srcClient, err := app.createEcrClient(src.region)
+	if err != nil {
+		return "", fmt.Errorf("failed to create ECR connection:\n%v", err)
+	}
+	//the target repository is written with the target credentials
+	dstCfg, err := app.targetAwsConfig(ctx, dst.region)
+	if err != nil {
+		return "", fmt.Errorf("failed to create ECR connection:\n%v", err)
+	}
+	dstClient := ecr.NewFromConfig(dstCfg)
+
+	//keep the tag or digest the source used, a digest pinned image gets no tag that could collide
+	//on an immutable repository or move on a mutable one
+	dst.tag = src.tag
+	dst.digest = ""
+
+	digest, err := copyEcrManifest(ctx, srcClient, dstClient, src, dst, src.imageId(), dst.tag != "")
+	if err != nil {
+		return "", err
+	}
+	if dst.tag == "" {
+		return dst.repositoryUri() + "@" + digest, nil
+	}
+	return dst.repositoryUri() + ":" + dst.tag, nil
+}
+
+// copyEcrManifest copies the children of an index first, only the top level manifest is tagged
+func copyEcrManifest(ctx context.Context, srcClient *ecr.Client, dstClient *ecr.Client, src ecrImage, dst ecrImage, id ecrtypes.ImageIdentifier, tagged bool) (string, error) {
+	images, err := srcClient.BatchGetImage(ctx, &ecr.BatchGetImageInput{
+		RegistryId:         aws.String(src.account),
+		RepositoryName:     aws.String(src.repository),
+		ImageIds:           []ecrtypes.ImageIdentifier{id},
+		AcceptedMediaTypes: ecrManifestMediaTypes,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read image manifest:\n%v", err)
+	}
+	if len(images.Images) == 0 {
+		return "", fmt.Errorf("image %s not found in %s", aws.ToString(id.ImageTag)+aws.ToString(id.ImageDigest), src.repository)
+	}
+	image := images.Images[0]
+
+	var manifest imageManifest
+	if err := json.Unmarshal([]byte(aws.ToString(image.ImageManifest)), &manifest); err != nil {
+		return "", fmt.Errorf("failed to read image manifest:\n%v", err)
+	}
+
+	for _, child := range manifest.Manifests {
+		_, err := copyEcrManifest(ctx, srcClient, dstClient, src, dst, ecrtypes.ImageIdentifier{ImageDigest: aws.String(child.Digest)}, false)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var blobs []string
+	if manifest.Config != nil {
+		blobs = append(blobs, manifest.Config.Digest)
+	}
+	for _, layer := range manifest.Layers {
+		blobs = append(blobs, layer.Digest)
+	}
+	if err := copyEcrBlobs(ctx, srcClient, dstClient, src, dst, blobs); err != nil {
+		return "", err
+	}
+
+	put := &ecr.PutImageInput{
+		RegistryId:             aws.String(dst.account),
+		RepositoryName:         aws.String(dst.repository),
+		ImageManifest:          image.ImageManifest,
+		ImageManifestMediaType: image.ImageManifestMediaType,
+	}
+	if tagged {
+		put.ImageTag = aws.String(dst.tag)
+	} else {
+		put.ImageDigest = image.ImageId.ImageDigest
+	}
+	_, err = dstClient.PutImage(ctx, put)
+	var exists *ecrtypes.ImageAlreadyExistsException
+	if err != nil && !errors.As(err, &exists) {
+		return "", fmt.Errorf("failed to put image into %s:\n%v", dst.repository, err)
+	}
+	return aws.ToString(image.ImageId.ImageDigest), nil
+}
+
+func copyEcrBlobs(ctx context.Context, srcClient *ecr.Client, dstClient *ecr.Client, src ecrImage, dst ecrImage, digests []string) error {
+	if len(digests) == 0 {
+		return nil
+	}
+	check, err := dstClient.BatchCheckLayerAvailability(ctx, &ecr.BatchCheckLayerAvailabilityInput{
+		RegistryId:     aws.String(dst.account),
+		RepositoryName: aws.String(dst.repository),
+		LayerDigests:   digests,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check layers in %s:\n%v", dst.repository, err)
+	}
+	available := map[string]bool{}
+	for _, layer := range check.Layers {
+		if layer.LayerAvailability == ecrtypes.LayerAvailabilityAvailable {
+			available[aws.ToString(layer.LayerDigest)] = true
+		}
+	}
+
+	for _, digest := range digests {
+		if available[digest] {
+			continue
+		}
+		download, err := srcClient.GetDownloadUrlForLayer(ctx, &ecr.GetDownloadUrlForLayerInput{
+			RegistryId:     aws.String(src.account),
+			RepositoryName: aws.String(src.repository),
+			LayerDigest:    aws.String(digest),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get layer %s:\n%v", digest, err)
+		}
+		if err := uploadEcrBlob(ctx, dstClient, dst, digest, aws.ToString(download.DownloadUrl)); err != nil {
+			return err
+		}
+		available[digest] = true
+	}
+	return nil
+}
+
+func uploadEcrBlob(ctx context.Context, dstClient *ecr.Client, dst ecrImage, digest string, downloadUrl string) error {
+	resp, err := httpGetWithRetry(downloadUrl)
+	if err != nil {
+		return fmt.Errorf("failed to download layer %s:\n%v", digest, err)
+	}
+	defer resp.Body.Close()
+
+	upload, err := dstClient.InitiateLayerUpload(ctx, &ecr.InitiateLayerUploadInput{
+		RegistryId:     aws.String(dst.account),
+		RepositoryName: aws.String(dst.repository),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start layer upload to %s:\n%v", dst.repository, err)
+	}
+	partSize := int64(ecrDefaultPartSize)
+	if upload.PartSize != nil && *upload.PartSize > 0 {
+		partSize = *upload.PartSize
+	}
+
+	buf := make([]byte, partSize)
+	var offset int64
+	for {
+		n, readErr := io.ReadFull(resp.Body, buf)
+		if n > 0 {
+			_, err = dstClient.UploadLayerPart(ctx, &ecr.UploadLayerPartInput{
+				RegistryId:     aws.String(dst.account),
+				RepositoryName: aws.String(dst.repository),
+				UploadId:       upload.UploadId,
+				LayerPartBlob:  buf[:n],
+				PartFirstByte:  aws.Int64(offset),
+				PartLastByte:   aws.Int64(offset + int64(n) - 1),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to upload layer %s:\n%v", digest, err)
+			}
+			offset += int64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to download layer %s:\n%v", digest, readErr)
+		}
+	}
+
+	_, err = dstClient.CompleteLayerUpload(ctx, &ecr.CompleteLayerUploadInput{
+		RegistryId:     aws.String(dst.account),
+		RepositoryName: aws.String(dst.repository),
+		UploadId:       upload.UploadId,
+		LayerDigests:   []string{digest},
+	})
+	var exists *ecrtypes.LayerAlreadyExistsException
+	if err != nil && !errors.As(err, &exists) {
+		return fmt.Errorf("failed to complete layer upload %s:\n%v", digest, err)
+	}
+	return nil
+}
@@ -7,7 +7,24 @@ func getHelp() string {
 	// description := "This utility allows you to manipulate AWS resources easily"
 	keySecret := "The AWS Key & Secret information for API access to your AWS environment. Elevated permissions is suggested."
 	token := "If you are given rotating session credentials then you will need to enter a session token here. Please note this is not needed if you use the CLI access keys assigned from a static IAM user."
-	lambda := "Lambda menu where you can List, Clone & Upgrade Lambda functions. It will upgrade to the latest version of that Runtime. Clone + Upgrade does both actions in 1 shot. Useful for cloning unsupported runtimes in AWS."
+	lambda := "Lambda menu where you can List, Clone & Upgrade Lambda functions. It will upgrade to the latest version of that Runtime's own family, after scanning the code and layers for anything the new version breaks. Clone + Upgrade does both actions in 1 shot. Useful for cloning unsupported runtimes in AWS. On the clone confirmation screen press 'd' to create the event source mappings of that run disabled. Runtime Deprecation Report lists functions whose runtime is deprecated or close to it, and Revert From Journal puts back a change recorded in journal.jsonl, except code, architectures and environment variables which are only recorded as hashes."
+	cloneOptions := "Settings that change how clones and upgrades are made, each entry describes what to type in. Remember to Save Settings to keep them.\n" +
+		"  Clone Version History: republishes every published version of the source on the clone.\n" +
+		"  Sync Existing Clones: updates a clone that already exists to match its source instead of failing.\n" +
+		"  Provenance Tags: tags clones with cloned-from, cloned-at and cloned-by.\n" +
+		"  Keep Partial Clone On Failure: leaves a clone that failed part way in place instead of rolling it back.\n" +
+		"  Allow Incompatible Layers: upgrades even when a layer does not declare the new runtime.\n" +
+		"  Smoke Test After Upgrade/Clone: invokes the function with its saved test event from the Payload Directory.\n" +
+		"  Runtime Pins: holds an upgrade at a chosen runtime instead of the newest.\n" +
+		"  Name Template / Name Pattern: names clones with a template instead of New Text.\n" +
+		"  Image Repository: ECR repository container images are copied into before cloning.\n" +
+		"  Staging Bucket: S3 bucket code packages over 50 MB are uploaded through.\n" +
+		"  Target Region / Target Role ARN: create the clone in another region or account.\n" +
+		"  Tag and Env options: change the tags and environment variables a clone gets from its source.\n" +
+		"  Canary options: shift an alias to the upgraded version step by step with a health check after each step.\n" +
+		"  Deprecation Window: how many days ahead a deprecation shows as approaching.\n" +
+		"  Wait Timeouts: how long a clone or upgrade waits for lambda to finish.\n" +
+		"  Resource Rewrites: arns and ids to swap when cloning into another region or account."
 	glue := "Glue jobs where you can List, Clone & Upgrade"
 	addText := "New text to add to the name of the object that you are cloning. The clone function uses the original name of the selected object and adds whatever text you put in here. It appends this text to the original name. This is a mandatory field to avoid duplicate function entries. For more control on where to add this New text use Replace Text field."
 	replaceText := "Text you want to remove and replace with New text. Text entered here will get replaced with the New Text regardless of it's location in the name of the object giving you more control on where to add New Text. If the Replace Text string is not found or if you leave this entry blank then New Text will always default to append to the end of the object name."
@@ -16,6 +33,7 @@ func getHelp() string {
 		lipgloss.NewStyle().Foreground(lipgloss.Color("112")).Bold(true).Render("Key/Secret: ") + keySecret + "\n\n" +
 		lipgloss.NewStyle().Foreground(lipgloss.Color("112")).Bold(true).Render("Token: ") + token + "\n\n" +
 		lipgloss.NewStyle().Foreground(lipgloss.Color("112")).Bold(true).Render("Lambda: ") + lambda + "\n\n" +
+		lipgloss.NewStyle().Foreground(lipgloss.Color("112")).Bold(true).Render("Clone Options: ") + cloneOptions + "\n\n" +
 		lipgloss.NewStyle().Foreground(lipgloss.Color("112")).Bold(true).Render("Glue: ") + glue + "\n\n" +
 		lipgloss.NewStyle().Foreground(lipgloss.Color("112")).Bold(true).Render("New Text: ") + addText + "\n\n" +
 		lipgloss.NewStyle().Foreground(lipgloss.Color("112")).Bold(true).Render("Replace Text: ") + replaceText
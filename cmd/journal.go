@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// journalFileName holds one JSON entry per line, next to the settings file
+const journalFileName = "journal.jsonl"
+
+// journalEntry has no Old for functions a clone created
+type journalEntry struct {
+	Time         time.Time      `json:"time"`
+	Action       string         `json:"action"`
+	Function     string         `json:"function"`
+	Region       string         `json:"region"`
+	RoleArn      string         `json:"rolearn,omitempty"`
+	Identity     string         `json:"identity"`
+	Alias        string         `json:"alias,omitempty"`
+	AliasVersion string         `json:"aliasversion,omitempty"`
+	Old          *journalConfig `json:"old,omitempty"`
+	New          *journalConfig `json:"new,omitempty"`
+}
+
+// journalConfig keeps only a hash of each environment value, they may hold secrets. Architectures change
+// with the code, they are recorded but not reverted.
+type journalConfig struct {
+	Runtime          string            `json:"runtime,omitempty"`
+	Handler          string            `json:"handler,omitempty"`
+	Role             string            `json:"role"`
+	Description      string            `json:"description"`
+	MemorySize       int32             `json:"memorysize"`
+	Timeout          int32             `json:"timeout"`
+	EphemeralStorage int32             `json:"ephemeralstorage,omitempty"`
+	Environment      map[string]string `json:"environmenthashes,omitempty"`
+	Layers           []string          `json:"layers,omitempty"`
+	SubnetIds        []string          `json:"subnetids,omitempty"`
+	SecurityGroupIds []string          `json:"securitygroupids,omitempty"`
+	Ipv6DualStack    bool              `json:"ipv6dualstack,omitempty"`
+	DeadLetterTarget string            `json:"deadlettertarget,omitempty"`
+	KMSKeyArn        string            `json:"kmskeyarn,omitempty"`
+	TracingMode      string            `json:"tracingmode,omitempty"`
+	Logging          *journalLogging   `json:"logging,omitempty"`
+	SnapStart        string            `json:"snapstart,omitempty"`
+	Architectures    []string          `json:"architectures,omitempty"`
+	CodeSha256       string            `json:"codesha256"`
+	RevisionId       string            `json:"revisionid"`
+}
+
+type journalLogging struct {
+	Format              string `json:"format"`
+	Group               string `json:"group,omitempty"`
+	ApplicationLogLevel string `json:"applicationloglevel,omitempty"`
+	SystemLogLevel      string `json:"systemloglevel,omitempty"`
+}
+
+func (l *journalLogging) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.TrimRight(strings.Join([]string{l.Format, l.Group, l.ApplicationLogLevel, l.SystemLogLevel}, " "), " ")
+}
+
+func snapshotConfig(cfg *types.FunctionConfiguration) *journalConfig {
+	snapshot := &journalConfig{
+		Runtime:     string(cfg.Runtime),
+		Handler:     aws.ToString(cfg.Handler),
+		Role:        aws.ToString(cfg.Role),
+		Description: aws.ToString(cfg.Description),
+		MemorySize:  aws.ToInt32(cfg.MemorySize),
+		Timeout:     aws.ToInt32(cfg.Timeout),
+		CodeSha256:  aws.ToString(cfg.CodeSha256),
+		RevisionId:  aws.ToString(cfg.RevisionId),
+	}
+	if cfg.EphemeralStorage != nil {
+		snapshot.EphemeralStorage = aws.ToInt32(cfg.EphemeralStorage.Size)
+	}
+	if cfg.Environment != nil {
+		snapshot.Environment = hashEnvironment(cfg.Environment.Variables)
+	}
+	for _, layer := range cfg.Layers {
+		snapshot.Layers = append(snapshot.Layers, aws.ToString(layer.Arn))
+	}
+	if cfg.VpcConfig != nil {
+		snapshot.SubnetIds = cfg.VpcConfig.SubnetIds
+		snapshot.SecurityGroupIds = cfg.VpcConfig.SecurityGroupIds
+		snapshot.Ipv6DualStack = aws.ToBool(cfg.VpcConfig.Ipv6AllowedForDualStack)
+	}
+	if cfg.DeadLetterConfig != nil {
+		snapshot.DeadLetterTarget = aws.ToString(cfg.DeadLetterConfig.TargetArn)
+	}
+	snapshot.KMSKeyArn = aws.ToString(cfg.KMSKeyArn)
+	if cfg.TracingConfig != nil {
+		snapshot.TracingMode = string(cfg.TracingConfig.Mode)
+	}
+	if cfg.LoggingConfig != nil {
+		snapshot.Logging = &journalLogging{
+			Format:              string(cfg.LoggingConfig.LogFormat),
+			Group:               aws.ToString(cfg.LoggingConfig.LogGroup),
+			ApplicationLogLevel: string(cfg.LoggingConfig.ApplicationLogLevel),
+			SystemLogLevel:      string(cfg.LoggingConfig.SystemLogLevel),
+		}
+	}
+	if cfg.SnapStart != nil {
+		snapshot.SnapStart = string(cfg.SnapStart.ApplyOn)
+	}
+	for _, architecture := range cfg.Architectures {
+		snapshot.Architectures = append(snapshot.Architectures, string(architecture))
+	}
+	return snapshot
+}
+
+func hashEnvironment(vars map[string]string) map[string]string {
+	if len(vars) == 0 {
+		return nil
+	}
+	hashes := map[string]string{}
+	for k, v := range vars {
+		sum := sha256.Sum256([]byte(v))
+		hashes[k] = "sha256:" + hex.EncodeToString(sum[:6])
+	}
+	return hashes
+}
+
+func functionSnapshot(ctx context.Context, clientLamb *lambda.Client, functionName string) (*journalConfig, error) {
+	result, err := clientLamb.GetFunction(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(functionName)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get function details:\n%v", err)
+	}
+	return snapshotConfig(result.Configuration), nil
+}
+
+func identityOf(ctx context.Context, cfg aws.Config) string {
+	identity, err := callerIdentity(ctx, cfg)
+	if err != nil {
+		return "unknown"
+	}
+	return aws.ToString(identity.Arn)
+}
+
+// journal skips entries whose configuration did not change, a failure to write is a warning on the
+// report rather than failing a change that already happened
+func (app *applicationMain) journal(ctx context.Context, cfg aws.Config, entry journalEntry, report *lambdaReport) {
+	entry.Time = time.Now().UTC()
+	entry.Region = cfg.Region
+	entry.Identity = identityOf(ctx, cfg)
+	var err error
+	entry.New, err = functionSnapshot(ctx, lambda.NewFromConfig(cfg), entry.Function)
+	if err == nil && entry.Old != nil && entry.Alias == "" && len(journalDiff(entry.Old, entry.New)) == 0 {
+		return
+	}
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(entry)
+	}
+	if err == nil {
+		var file *os.File
+		file, err = os.OpenFile(journalFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = file.Write(append(data, '\n'))
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		report.warn("the change could not be written to %s: %v", journalFileName, err)
+	}
+}
+
+// loadJournal returns newest first and skips lines it cannot read
+func loadJournal() ([]journalEntry, error) {
+	file, err := os.Open(journalFileName)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal:\n%v", err)
+	}
+	defer file.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(a, b int) bool { return entries[a].Time.After(entries[b].Time) })
+	return entries, scanner.Err()
+}
+
+func envKeysChanged(a map[string]string, b map[string]string) []string {
+	var keys []string
+	for k, v := range a {
+		if b[k] != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (e journalEntry) label() string {
+	change := ""
+	switch {
+	case e.Old == nil && e.New != nil:
+		change = "created on " + e.New.Runtime
+	case e.Old != nil && e.New != nil && e.Old.Runtime != e.New.Runtime:
+		change = e.Old.Runtime + " -> " + e.New.Runtime
+	case e.Old != nil && e.New != nil:
+		change = fmt.Sprintf("%d settings changed", len(journalDiff(e.Old, e.New)))
+	}
+	return fmt.Sprintf("%s  %-14s %s  %s  (%s)", e.Time.Local().Format("2006-01-02 15:04"), e.Action, e.Function, change, e.Region)
+}
+
+func journalDiff(a *journalConfig, b *journalConfig) []string {
+	var diff []string
+	add := func(name string, x string, y string) {
+		if x != y {
+			diff = append(diff, fmt.Sprintf("%s: %s -> %s", name, x, y))
+		}
+	}
+	add("runtime", a.Runtime, b.Runtime)
+	add("handler", a.Handler, b.Handler)
+	add("role", a.Role, b.Role)
+	add("description", a.Description, b.Description)
+	add("memory", fmt.Sprint(a.MemorySize), fmt.Sprint(b.MemorySize))
+	add("timeout", fmt.Sprint(a.Timeout), fmt.Sprint(b.Timeout))
+	add("ephemeral storage", fmt.Sprint(a.EphemeralStorage), fmt.Sprint(b.EphemeralStorage))
+	add("layers", strings.Join(a.Layers, ", "), strings.Join(b.Layers, ", "))
+	add("vpc subnets", strings.Join(a.SubnetIds, ", "), strings.Join(b.SubnetIds, ", "))
+	add("vpc security groups", strings.Join(a.SecurityGroupIds, ", "), strings.Join(b.SecurityGroupIds, ", "))
+	add("vpc ipv6 dual stack", fmt.Sprint(a.Ipv6DualStack), fmt.Sprint(b.Ipv6DualStack))
+	add("dead letter queue", a.DeadLetterTarget, b.DeadLetterTarget)
+	add("kms key", a.KMSKeyArn, b.KMSKeyArn)
+	add("tracing", a.TracingMode, b.TracingMode)
+	add("logging", a.Logging.String(), b.Logging.String())
+	add("snapstart", a.SnapStart, b.SnapStart)
+	add("architectures", strings.Join(a.Architectures, ", "), strings.Join(b.Architectures, ", "))
+	add("code", a.CodeSha256, b.CodeSha256)
+	for _, line := range envDiff(a.Environment, b.Environment) {
+		diff = append(diff, "environment "+line)
+	}
+	return diff
+}
+
+func (app *applicationMain) journalClient(ctx context.Context, entry journalEntry) (*lambda.Client, aws.Config, error) {
+	if entry.RoleArn != "" && entry.RoleArn != app.TargetRoleArn {
+		return nil, aws.Config{}, fmt.Errorf("%s was changed through %s, set it as the Target Role ARN to revert it", entry.Function, entry.RoleArn)
+	}
+	var cfg aws.Config
+	var err error
+	if entry.RoleArn != "" {
+		cfg, err = app.targetAwsConfig(ctx, entry.Region)
+	} else {
+		cfg, err = app.awsConfig(ctx, entry.Region)
+	}
+	if err != nil {
+		return nil, cfg, fmt.Errorf("failed to create Lambda connection:\n%v", err)
+	}
+	return lambda.NewFromConfig(cfg), cfg, nil
+}
+
+func (app *applicationMain) revertPreview(entry journalEntry) (string, error) {
+	if entry.Old == nil {
+		return "", fmt.Errorf("%s was created by a %s, there is no earlier configuration to restore, delete the function to undo it", entry.Function, entry.Action)
+	}
+	ctx := context.TODO()
+	clientLamb, _, err := app.journalClient(ctx, entry)
+	if err != nil {
+		return "", err
+	}
+	current, err := functionSnapshot(ctx, clientLamb, entry.Function)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{fmt.Sprintf("Revert %s of %s from %s", entry.Action, entry.Function, entry.Time.Local().Format("2006-01-02 15:04")), ""}
+	switch {
+	case entry.New == nil:
+		lines = append(lines, "WARNING: no recorded post-change state, later changes to the function cannot be shown and are overwritten", "")
+	case current.RevisionId != entry.New.RevisionId:
+		lines = append(lines, "WARNING: the function has changed since this entry, the revert overwrites:")
+		for _, line := range journalDiff(entry.New, current) {
+			lines = append(lines, "  "+line)
+		}
+		lines = append(lines, "")
+	}
+	changes := journalDiff(current, entry.Old)
+	if len(changes) == 0 {
+		lines = append(lines, "The configuration already matches the entry, nothing to change")
+	}
+	for _, line := range changes {
+		if revertable(line) {
+			lines = append(lines, "  "+line)
+		}
+	}
+	if current.CodeSha256 != entry.Old.CodeSha256 {
+		lines = append(lines, "  code differs and is not reverted, only the configuration is")
+	}
+	if architectures := strings.Join(entry.Old.Architectures, ", "); len(entry.Old.Architectures) > 0 && architectures != strings.Join(current.Architectures, ", ") {
+		lines = append(lines, "  architectures differ and are not reverted, they change with the code: "+architectures)
+	}
+	if keys := envKeysChanged(current.Environment, entry.Old.Environment); len(keys) > 0 {
+		lines = append(lines, "  environment variables differ and are not reverted, set them by hand: "+strings.Join(keys, ", "))
+	}
+	if entry.Alias != "" {
+		lines = append(lines, fmt.Sprintf("  alias %s -> version %s", entry.Alias, entry.AliasVersion))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func revertable(line string) bool {
+	return !strings.HasPrefix(line, "code:") && !strings.HasPrefix(line, "environment ") && !strings.HasPrefix(line, "architectures:")
+}
+
+// revertSettings only sends what changed, lambda refuses some settings (SnapStart, log levels) where
+// they do not apply even when unset
+func revertSettings(input *lambda.UpdateFunctionConfigurationInput, current *journalConfig, old *journalConfig) {
+	if strings.Join(current.SubnetIds, ",") != strings.Join(old.SubnetIds, ",") ||
+		strings.Join(current.SecurityGroupIds, ",") != strings.Join(old.SecurityGroupIds, ",") || current.Ipv6DualStack != old.Ipv6DualStack {
+		//empty lists take the function out of its vpc
+		input.VpcConfig = &types.VpcConfig{SubnetIds: old.SubnetIds, SecurityGroupIds: old.SecurityGroupIds}
+		if input.VpcConfig.SubnetIds == nil {
+			input.VpcConfig.SubnetIds = []string{}
+		}
+		if input.VpcConfig.SecurityGroupIds == nil {
+			input.VpcConfig.SecurityGroupIds = []string{}
+		}
+		if len(old.SubnetIds) > 0 {
+			input.VpcConfig.Ipv6AllowedForDualStack = aws.Bool(old.Ipv6DualStack)
+		}
+	}
+	if current.DeadLetterTarget != old.DeadLetterTarget {
+		input.DeadLetterConfig = &types.DeadLetterConfig{TargetArn: aws.String(old.DeadLetterTarget)}
+	}
+	if current.KMSKeyArn != old.KMSKeyArn {
+		input.KMSKeyArn = aws.String(old.KMSKeyArn)
+	}
+	if current.TracingMode != old.TracingMode && old.TracingMode != "" {
+		input.TracingConfig = &types.TracingConfig{Mode: types.TracingMode(old.TracingMode)}
+	}
+	if current.Logging.String() != old.Logging.String() && old.Logging != nil {
+		input.LoggingConfig = &types.LoggingConfig{
+			LogFormat:           types.LogFormat(old.Logging.Format),
+			ApplicationLogLevel: types.ApplicationLogLevel(old.Logging.ApplicationLogLevel),
+			SystemLogLevel:      types.SystemLogLevel(old.Logging.SystemLogLevel),
+		}
+		if old.Logging.Group != "" {
+			input.LoggingConfig.LogGroup = aws.String(old.Logging.Group)
+		}
+	}
+	if current.SnapStart != old.SnapStart && old.SnapStart != "" {
+		input.SnapStart = &types.SnapStart{ApplyOn: types.SnapStartApplyOn(old.SnapStart)}
+	}
+}
+
+func (app *applicationMain) revertJournalEntry(entry journalEntry) (*lambdaReport, error) {
+	report := newLambdaReport("Revert " + entry.Function)
+	if entry.Old == nil {
+		return report, fmt.Errorf("%s was created by a %s, there is no earlier configuration to restore", entry.Function, entry.Action)
+	}
+	ctx := context.TODO()
+	clientLamb, cfg, err := app.journalClient(ctx, entry)
+	if err != nil {
+		return report, err
+	}
+	if err = app.waitForFunctionUpdate(ctx, clientLamb, entry.Function); err != nil {
+		return report, err
+	}
+	before, err := functionSnapshot(ctx, clientLamb, entry.Function)
+	if err != nil {
+		return report, err
+	}
+
+	old := entry.Old
+	input := &lambda.UpdateFunctionConfigurationInput{
+		FunctionName: aws.String(entry.Function),
+		Role:         aws.String(old.Role),
+		Description:  aws.String(old.Description),
+		MemorySize:   aws.Int32(old.MemorySize),
+		Timeout:      aws.Int32(old.Timeout),
+		Layers:       old.Layers,
+	}
+	if input.Layers == nil {
+		input.Layers = []string{}
+	}
+	if old.Runtime != "" {
+		input.Runtime = types.Runtime(old.Runtime)
+		input.Handler = aws.String(old.Handler)
+	}
+	if old.EphemeralStorage > 0 {
+		input.EphemeralStorage = &types.EphemeralStorage{Size: aws.Int32(old.EphemeralStorage)}
+	}
+	revertSettings(input, before, old)
+	if _, err = clientLamb.UpdateFunctionConfiguration(ctx, input); err != nil {
+		return report, fmt.Errorf("failed to revert %s:\n%v", entry.Function, err)
+	}
+	if err = app.waitForFunctionUpdate(ctx, clientLamb, entry.Function); err != nil {
+		return report, err
+	}
+	for _, line := range journalDiff(before, old) {
+		if revertable(line) {
+			report.note("%s", line)
+		}
+	}
+	if architectures := strings.Join(old.Architectures, ", "); len(old.Architectures) > 0 && architectures != strings.Join(before.Architectures, ", ") {
+		report.warn("architectures are not reverted, they change with the code: %s", architectures)
+	}
+	if keys := envKeysChanged(before.Environment, old.Environment); len(keys) > 0 {
+		report.warn("environment variables are not reverted, set them by hand: %s", strings.Join(keys, ", "))
+	}
+
+	if entry.Alias != "" {
+		_, err = clientLamb.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+			FunctionName:    aws.String(entry.Function),
+			Name:            aws.String(entry.Alias),
+			FunctionVersion: aws.String(entry.AliasVersion),
+			RoutingConfig:   &types.AliasRoutingConfiguration{AdditionalVersionWeights: map[string]float64{}},
+		})
+		if err != nil {
+			return report, fmt.Errorf("configuration reverted but alias %s could not be moved back:\n%v", entry.Alias, err)
+		}
+		report.note("alias %s -> version %s", entry.Alias, entry.AliasVersion)
+	}
+
+	app.journal(ctx, cfg, journalEntry{Action: "revert", Function: entry.Function, RoleArn: entry.RoleArn, Old: before}, report)
+	return report, nil
+}
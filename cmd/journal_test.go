@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func TestRevertSettings(t *testing.T) {
+	vpc := journalConfig{SubnetIds: []string{"subnet-a"}, SecurityGroupIds: []string{"sg-a"}, TracingMode: "PassThrough", Logging: &journalLogging{Format: "Text", Group: "/aws/lambda/orders"}, SnapStart: "None"}
+	tests := []struct {
+		name    string
+		current journalConfig
+		old     journalConfig
+		check   func(t *testing.T, input *lambda.UpdateFunctionConfigurationInput)
+	}{
+		{"unchanged sends nothing", vpc, vpc, func(t *testing.T, input *lambda.UpdateFunctionConfigurationInput) {
+			if input.VpcConfig != nil || input.DeadLetterConfig != nil || input.KMSKeyArn != nil || input.TracingConfig != nil || input.LoggingConfig != nil || input.SnapStart != nil {
+				t.Errorf("unchanged settings were sent: %+v", input)
+			}
+		}},
+		{"leaves a vpc it was not in", vpc, journalConfig{TracingMode: "PassThrough"}, func(t *testing.T, input *lambda.UpdateFunctionConfigurationInput) {
+			if input.VpcConfig == nil || input.VpcConfig.SubnetIds == nil || len(input.VpcConfig.SubnetIds) != 0 || len(input.VpcConfig.SecurityGroupIds) != 0 {
+				t.Errorf("vpc = %+v, want empty lists", input.VpcConfig)
+			}
+			if input.VpcConfig != nil && input.VpcConfig.Ipv6AllowedForDualStack != nil {
+				t.Errorf("dual stack set without subnets")
+			}
+		}},
+		{"dead letter queue and kms key removed", journalConfig{DeadLetterTarget: "arn:aws:sqs:us-east-1:123456789012:dlq", KMSKeyArn: "arn:aws:kms:us-east-1:123456789012:key/k"}, journalConfig{}, func(t *testing.T, input *lambda.UpdateFunctionConfigurationInput) {
+			if input.DeadLetterConfig == nil || aws.ToString(input.DeadLetterConfig.TargetArn) != "" || input.KMSKeyArn == nil || *input.KMSKeyArn != "" {
+				t.Errorf("dead letter %+v kms %v, want both cleared", input.DeadLetterConfig, input.KMSKeyArn)
+			}
+		}},
+		{"tracing, logging and snapstart restored", journalConfig{TracingMode: "Active", Logging: &journalLogging{Format: "JSON", ApplicationLogLevel: "DEBUG", SystemLogLevel: "INFO"}, SnapStart: "PublishedVersions"}, vpc, func(t *testing.T, input *lambda.UpdateFunctionConfigurationInput) {
+			if input.TracingConfig == nil || input.TracingConfig.Mode != types.TracingModePassThrough {
+				t.Errorf("tracing = %+v", input.TracingConfig)
+			}
+			if input.LoggingConfig == nil || input.LoggingConfig.LogFormat != types.LogFormatText || input.LoggingConfig.ApplicationLogLevel != "" || aws.ToString(input.LoggingConfig.LogGroup) != "/aws/lambda/orders" {
+				t.Errorf("logging = %+v", input.LoggingConfig)
+			}
+			if input.SnapStart == nil || input.SnapStart.ApplyOn != types.SnapStartApplyOnNone {
+				t.Errorf("snapstart = %+v", input.SnapStart)
+			}
+		}},
+		{"settings that were not recorded are left alone", journalConfig{TracingMode: "Active", Logging: &journalLogging{Format: "JSON"}, SnapStart: "PublishedVersions"}, journalConfig{}, func(t *testing.T, input *lambda.UpdateFunctionConfigurationInput) {
+			if input.TracingConfig != nil || input.LoggingConfig != nil || input.SnapStart != nil {
+				t.Errorf("tracing %+v logging %+v snapstart %+v, want none", input.TracingConfig, input.LoggingConfig, input.SnapStart)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &lambda.UpdateFunctionConfigurationInput{}
+			revertSettings(input, &tt.current, &tt.old)
+			tt.check(t, input)
+		})
+	}
+}
+
+func TestJournalDiffArchitectures(t *testing.T) {
+	diff := journalDiff(&journalConfig{Architectures: []string{"x86_64"}}, &journalConfig{Architectures: []string{"arm64"}})
+	if len(diff) != 1 || diff[0] != "architectures: x86_64 -> arm64" {
+		t.Fatalf("journalDiff = %q", diff)
+	}
+	if revertable(diff[0]) {
+		t.Errorf("architectures are listed as reverted")
+	}
+}
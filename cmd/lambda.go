@@ -3,8 +3,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
-	"net/http"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -13,12 +11,16 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 )
 
-func (app *applicationMain) createLambdaClient() (*lambda.Client, error) {
-	ctx := context.Background()
+func (app *applicationMain) awsConfig(ctx context.Context, region string) (aws.Config, error) {
 	customCreds := aws.NewCredentialsCache(
 		credentials.NewStaticCredentialsProvider(app.AwsKey, app.AwsSecret, app.SessionToken),
 	)
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(customCreds), config.WithRegion(app.Region))
+	return config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(customCreds), config.WithRegion(region))
+}
+
+func (app *applicationMain) createLambdaClient() (*lambda.Client, error) {
+	ctx := context.Background()
+	cfg, err := app.awsConfig(ctx, app.Region)
 	if err != nil {
 		return nil, err
 	}
@@ -27,211 +29,86 @@ func (app *applicationMain) createLambdaClient() (*lambda.Client, error) {
 	return client, nil
 }
 
-func (app *applicationMain) cloneLambda(functionName string, functionNameNew string, upgrade2 bool) error {
-	ctx := context.Background()
-	//create lambda client
-	clientLamb, err := app.createLambdaClient()
-	if err != nil {
-		return fmt.Errorf("failed to create Lambda connection:\n%v", err)
-	}
-
-	//get the lambda function
-	result, err := clientLamb.GetFunction(ctx, &lambda.GetFunctionInput{
-		FunctionName: aws.String(functionName),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to get function details:\n%v", err)
+func supportsSnapStart(runtime types.Runtime) bool {
+	switch runtime {
+	case types.RuntimeJava11, types.RuntimeJava17, types.RuntimeJava21,
+		types.RuntimePython312, types.RuntimePython313, types.RuntimeDotnet8:
+		return true
 	}
+	return false
+}
 
-	//download the lambda Zip file
-	if result.Code == nil || result.Code.Location == nil {
-		return fmt.Errorf("no code location found for the function")
-	}
-	resp, err := http.Get(*result.Code.Location)
-	if err != nil {
-		return fmt.Errorf("failed to download code function:\n%v", err)
-	}
-	defer resp.Body.Close()
-
-	zipBytes, err := io.ReadAll(resp.Body)
+func (app *applicationMain) upgradeLambda(lambdaFunctionName string) (*lambdaReport, error) {
+	report := newLambdaReport(lambdaFunctionName)
+	clientLamb, err := app.createLambdaClient()
 	if err != nil {
-		return fmt.Errorf("failed to read code zip file content:\n%v", err)
+		return report, fmt.Errorf("failed to create Lambda connection:\n%v", err)
 	}
 
-	//layers
-	var layerArns []string
-	if result.Configuration.Layers != nil {
-		for _, layer := range result.Configuration.Layers {
-			if layer.Arn != nil {
-				layerArns = append(layerArns, *layer.Arn)
-			}
-		}
+	ctx := context.TODO()
+	cfg, newRuntime, err := app.upgradeTarget(ctx, clientLamb, lambdaFunctionName, report)
+	if err != nil || cfg == nil {
+		return report, err
 	}
 
-	//environment variables
-	var env *types.Environment
-	if result.Configuration.Environment != nil {
-		env = &types.Environment{
-			Variables: result.Configuration.Environment.Variables,
-		}
+	//the runtime may have changed even when waiting for the update failed, the old one is journaled either way
+	err = app.setRuntime(ctx, clientLamb, lambdaFunctionName, newRuntime)
+	if awsCfg, cfgErr := app.awsConfig(ctx, app.Region); cfgErr == nil {
+		app.journal(ctx, awsCfg, journalEntry{Action: "upgrade", Function: lambdaFunctionName, Old: snapshotConfig(cfg)}, report)
 	}
-
-	//vpc config
-	var vpcConfig *types.VpcConfig
-	if result.Configuration.VpcConfig != nil {
-		vpcConfig = &types.VpcConfig{
-			SecurityGroupIds: result.Configuration.VpcConfig.SecurityGroupIds,
-			SubnetIds:        result.Configuration.VpcConfig.SubnetIds,
-		}
-	}
-
-	//runtime selection
-	runtimeToUse := result.Configuration.Runtime
-	if upgrade2 {
-		runtimeToUse = types.RuntimePython313
-	}
-	//create the new lambda
-	newLamb, err := clientLamb.CreateFunction(ctx, &lambda.CreateFunctionInput{
-		FunctionName: aws.String(functionNameNew),
-		Runtime:      runtimeToUse,
-		Role:         result.Configuration.Role,
-		Handler:      result.Configuration.Handler,
-		Code: &types.FunctionCode{
-			ZipFile: zipBytes,
-		},
-		Timeout:           result.Configuration.Timeout,
-		MemorySize:        result.Configuration.MemorySize,
-		Environment:       env,
-		Layers:            layerArns,
-		TracingConfig:     (*types.TracingConfig)(result.Configuration.TracingConfig),
-		Architectures:     result.Configuration.Architectures,
-		PackageType:       result.Configuration.PackageType,
-		Description:       result.Configuration.Description,
-		Publish:           *aws.Bool(true),
-		VpcConfig:         vpcConfig,
-		DeadLetterConfig:  result.Configuration.DeadLetterConfig,
-		FileSystemConfigs: result.Configuration.FileSystemConfigs,
-		EphemeralStorage:  result.Configuration.EphemeralStorage,
-	})
 	if err != nil {
-		return fmt.Errorf("failed to create a new lambda function:\n%v", err)
-	}
-
-	//copy tags
-	tagResp, err := clientLamb.ListTags(ctx, &lambda.ListTagsInput{
-		Resource: result.Configuration.FunctionArn,
-	})
-	if err == nil && len(tagResp.Tags) > 0 {
-		_, err = clientLamb.TagResource(ctx, &lambda.TagResourceInput{
-			Resource: newLamb.FunctionArn,
-			Tags:     tagResp.Tags,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to add tags to new Lambda function: %v", err)
-		}
+		return report, err
 	}
+	report.note("runtime %s -> %s", cfg.Runtime, newRuntime)
+	return report, app.smokeTest(ctx, clientLamb, lambdaFunctionName, "", []string{lambdaFunctionName}, report)
+}
 
-	// Copy Concurrency (if set).
-	concurrencyResp, err := clientLamb.GetFunctionConcurrency(ctx, &lambda.GetFunctionConcurrencyInput{
-		FunctionName: aws.String(functionName),
-	})
-	if err == nil && concurrencyResp.ReservedConcurrentExecutions != nil {
-		_, err = clientLamb.PutFunctionConcurrency(ctx, &lambda.PutFunctionConcurrencyInput{
-			FunctionName:                 aws.String(functionNameNew),
-			ReservedConcurrentExecutions: concurrencyResp.ReservedConcurrentExecutions,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to set concurrency on new Lambda function: %v", err)
-		}
+// upgradeTarget returns a nil configuration when there is nothing to upgrade
+func (app *applicationMain) upgradeTarget(ctx context.Context, clientLamb *lambda.Client, functionName string, report *lambdaReport) (*types.FunctionConfiguration, types.Runtime, error) {
+	if err := app.waitForFunctionUpdate(ctx, clientLamb, functionName); err != nil {
+		return nil, "", err
 	}
 
-	// Copy Event Source Mappings.
-	eventSrcResp, err := clientLamb.ListEventSourceMappings(ctx, &lambda.ListEventSourceMappingsInput{
+	//the newest runtime of the function's own family, or its pin
+	result, err := clientLamb.GetFunction(ctx, &lambda.GetFunctionInput{
 		FunctionName: aws.String(functionName),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to list event source mappings: %v", err)
+		return nil, "", fmt.Errorf("failed to get function details:\n%v", err)
 	}
-
-	for _, src := range eventSrcResp.EventSourceMappings {
-		// Determine if the mapping is enabled by comparing its State to "Enabled"
-		enabled := false
-		if src.State != nil && *src.State == "Enabled" {
-			enabled = true
-		}
-		_, err = clientLamb.CreateEventSourceMapping(ctx, &lambda.CreateEventSourceMappingInput{
-			EventSourceArn:                 src.EventSourceArn,
-			FunctionName:                   aws.String(functionNameNew),
-			BatchSize:                      src.BatchSize,
-			Enabled:                        aws.Bool(enabled),
-			MaximumBatchingWindowInSeconds: src.MaximumBatchingWindowInSeconds,
-			StartingPosition:               src.StartingPosition, // if applicable for your event source
-			// Add other necessary fields here.
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create event source mapping on new Lambda function: %v", err)
-		}
+	cfg := result.Configuration
+	if cfg.PackageType == types.PackageTypeImage {
+		report.warn("container image functions have no managed runtime, nothing to upgrade")
+		return nil, "", nil
 	}
-
-	//resource policies
-	policyResp, err := clientLamb.GetPolicy(ctx, &lambda.GetPolicyInput{
-		FunctionName: aws.String(functionName),
-	})
-	if err == nil && policyResp.Policy != nil {
-		_, err = clientLamb.AddPermission(ctx, &lambda.AddPermissionInput{
-			FunctionName: newLamb.FunctionArn,
-			StatementId:  aws.String("ClonePermission"),
-			Action:       aws.String("lambda:InvokeFunction"),
-			Principal:    aws.String("*"),
-			SourceArn:    newLamb.FunctionArn, // Adjust as needed.
-		})
-		if err != nil {
-			return fmt.Errorf("failed to add permission on new Lambda function: %v", err)
-		}
+	newRuntime, err := app.upgradeRuntime(functionName, cfg.Runtime)
+	if err != nil {
+		return nil, "", err
 	}
-
-	//aliases
-	aliasResp, err := clientLamb.ListAliases(ctx, &lambda.ListAliasesInput{
-		FunctionName: aws.String(functionName),
-	})
-	if err == nil {
-		for _, alias := range aliasResp.Aliases {
-			// NOTE: Aliases point to a published version. You may need to publish a new version
-			// for the new Lambda and then create aliases pointing to that version.
-			_, err = clientLamb.CreateAlias(ctx, &lambda.CreateAliasInput{
-				FunctionName:    aws.String(functionNameNew),
-				Name:            alias.Name,
-				FunctionVersion: alias.FunctionVersion,
-				Description:     alias.Description,
-			})
-			if err != nil {
-				return fmt.Errorf("failed to create alias on new Lambda function: %v", err)
-			}
-		}
+	if newRuntime == cfg.Runtime {
+		report.note("runtime %s already current", cfg.Runtime)
+		return nil, "", nil
 	}
-
-	return nil
-}
-
-func (app *applicationMain) upgradeLambda(lambdaFunctionName string) error {
-	clientLamb, err := app.createLambdaClient()
-	if err != nil {
-		return fmt.Errorf("failed to create Lambda connection:\n%v", err)
+	//layers that do not declare the new runtime fail at import time
+	if err = app.checkLayers(ctx, clientLamb, cfg, newRuntime, report); err != nil {
+		return nil, "", err
 	}
+	return cfg, newRuntime, nil
+}
 
-	newRuntime := types.RuntimePython313
-
+func (app *applicationMain) setRuntime(ctx context.Context, clientLamb *lambda.Client, functionName string, runtime types.Runtime) error {
 	input := &lambda.UpdateFunctionConfigurationInput{
-		FunctionName: aws.String(lambdaFunctionName),
-		Runtime:      newRuntime,
+		FunctionName: aws.String(functionName),
+		Runtime:      runtime,
 	}
 
-	_, err = clientLamb.UpdateFunctionConfiguration(context.TODO(), input)
+	_, err := clientLamb.UpdateFunctionConfiguration(ctx, input)
 	if err != nil {
 		return fmt.Errorf("failed to udpate Lambda function\n%v", err)
 	}
 
-	return nil
+	//the runtime change only counts once lambda reports it Successful
+	return app.waitForFunctionUpdate(ctx, clientLamb, functionName)
 }
 
 func (app *applicationMain) listAllLambdaFunctions() (LambdaItems [][]string, err error) {
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+const defaultCanaryWaitSeconds = 120
+
+var defaultCanarySteps = []int{10, 50}
+
+func (app *applicationMain) canarySteps() []int {
+	if len(app.CanarySteps) > 0 {
+		return app.CanarySteps
+	}
+	return defaultCanarySteps
+}
+
+func (app *applicationMain) canaryWait() time.Duration {
+	if app.CanaryWaitSeconds > 0 {
+		return time.Duration(app.CanaryWaitSeconds) * time.Second
+	}
+	return defaultCanaryWaitSeconds * time.Second
+}
+
+// parseCanarySteps keeps percentages between 1 and 99 in rising order
+func parseCanarySteps(value string) []int {
+	seen := map[int]bool{}
+	var steps []int
+	for _, field := range strings.Split(value, ",") {
+		step, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(field), "%"))
+		if err != nil || step < 1 || step > 99 || seen[step] {
+			continue
+		}
+		seen[step] = true
+		steps = append(steps, step)
+	}
+	sort.Ints(steps)
+	return steps
+}
+
+func formatCanarySteps(steps []int) string {
+	var fields []string
+	for _, step := range steps {
+		fields = append(fields, strconv.Itoa(step))
+	}
+	return strings.Join(fields, ", ")
+}
+
+// parseErrorRate turns the error rate check off for anything but a percentage
+func parseErrorRate(value string) float64 {
+	rate, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil || rate < 0 {
+		return 0
+	}
+	return rate
+}
+
+// canaryChecks refuses a canary without checks, its steps would move all traffic with nothing to go on
+func (app *applicationMain) canaryChecks() ([]string, error) {
+	var checks []string
+	if app.CanaryPayload != "" {
+		checks = append(checks, "smoke invocation")
+	}
+	if app.CanaryErrorRate > 0 {
+		checks = append(checks, fmt.Sprintf("error rate at most %.1f%%", app.CanaryErrorRate))
+	}
+	if len(checks) == 0 {
+		return nil, fmt.Errorf("Canary Alias %s has no health check, set Canary Smoke Payload or Canary Max Error Rate", app.CanaryAlias)
+	}
+	return checks, nil
+}
+
+// canaryUpgrade publishes the new runtime as a version and shifts the Canary Alias to it step by step,
+// a failed check points the alias back and puts $LATEST back on the old runtime
+func (app *applicationMain) canaryUpgrade(functionName string, progress func(string)) (*lambdaReport, error) {
+	report := newLambdaReport(functionName)
+	clientLamb, err := app.createLambdaClient()
+	if err != nil {
+		return report, fmt.Errorf("failed to create Lambda connection:\n%v", err)
+	}
+	ctx := context.TODO()
+	alias := app.CanaryAlias
+	if _, err = app.canaryChecks(); err != nil {
+		return report, err
+	}
+
+	aliasCfg, err := clientLamb.GetAlias(ctx, &lambda.GetAliasInput{
+		FunctionName: aws.String(functionName),
+		Name:         aws.String(alias),
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to get alias %s:\n%v", alias, err)
+	}
+	stable := aws.ToString(aliasCfg.FunctionVersion)
+	if stable == latestVersion {
+		return report, fmt.Errorf("alias %s points at $LATEST, it needs a published version to shift traffic from", alias)
+	}
+	if aliasCfg.RoutingConfig != nil && len(aliasCfg.RoutingConfig.AdditionalVersionWeights) > 0 {
+		return report, fmt.Errorf("alias %s already splits traffic, finish that rollout first", alias)
+	}
+
+	cfg, newRuntime, err := app.upgradeTarget(ctx, clientLamb, functionName, report)
+	if err != nil || cfg == nil {
+		return report, err
+	}
+	if err = canaryRollbackAllowed(cfg.Runtime, time.Now()); err != nil {
+		return report, err
+	}
+
+	progress(fmt.Sprintf("%s: runtime %s -> %s on $LATEST", functionName, cfg.Runtime, newRuntime))
+	if err = app.setRuntime(ctx, clientLamb, functionName, newRuntime); err != nil {
+		//the runtime may have changed even when waiting for the update failed
+		if awsCfg, cfgErr := app.awsConfig(ctx, app.Region); cfgErr == nil {
+			app.journal(ctx, awsCfg, journalEntry{Action: "upgrade", Function: functionName, Old: snapshotConfig(cfg)}, report)
+		}
+		return report, err
+	}
+	published, err := clientLamb.PublishVersion(ctx, &lambda.PublishVersionInput{
+		FunctionName: aws.String(functionName),
+		Description:  aws.String(fmt.Sprintf("runtime upgrade %s -> %s", cfg.Runtime, newRuntime)),
+	})
+	if err != nil {
+		return report, app.canaryRollback(ctx, clientLamb, functionName, stable, cfg.Runtime, report, progress,
+			fmt.Errorf("failed to publish version on %s:\n%v", newRuntime, err))
+	}
+	canary := aws.ToString(published.Version)
+	progress(fmt.Sprintf("%s: published version %s on %s", functionName, canary, newRuntime))
+
+	steps := app.canarySteps()
+	for i, step := range steps {
+		_, err = clientLamb.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+			FunctionName:    aws.String(functionName),
+			Name:            aws.String(alias),
+			FunctionVersion: aws.String(stable),
+			RoutingConfig: &types.AliasRoutingConfiguration{
+				AdditionalVersionWeights: map[string]float64{canary: float64(step) / 100},
+			},
+		})
+		if err != nil {
+			return report, app.canaryRollback(ctx, clientLamb, functionName, stable, cfg.Runtime, report, progress,
+				fmt.Errorf("failed to shift alias %s to %d%%:\n%v", alias, step, err))
+		}
+		progress(fmt.Sprintf("%s: step %d/%d, %d%% of %s on version %s, checking in %s", functionName, i+1, len(steps), step, alias, canary, app.canaryWait()))
+
+		start := time.Now()
+		time.Sleep(app.canaryWait())
+		if err = app.canaryHealthCheck(ctx, clientLamb, functionName, canary, start, report, progress); err != nil {
+			return report, app.canaryRollback(ctx, clientLamb, functionName, stable, cfg.Runtime, report, progress,
+				fmt.Errorf("health check failed at %d%%: %v", step, err))
+		}
+		progress(fmt.Sprintf("%s: step %d/%d healthy", functionName, i+1, len(steps)))
+	}
+
+	//promote, the alias points at the new version alone
+	_, err = clientLamb.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(functionName),
+		Name:            aws.String(alias),
+		FunctionVersion: aws.String(canary),
+		RoutingConfig:   &types.AliasRoutingConfiguration{AdditionalVersionWeights: map[string]float64{}},
+	})
+	if err != nil {
+		return report, app.canaryRollback(ctx, clientLamb, functionName, stable, cfg.Runtime, report, progress,
+			fmt.Errorf("failed to promote version %s on alias %s:\n%v", canary, alias, err))
+	}
+	progress(fmt.Sprintf("%s: %s now on version %s", functionName, alias, canary))
+	report.note("runtime %s -> %s", cfg.Runtime, newRuntime)
+	report.note("alias %s moved from version %s to %s in steps of %s%%", alias, stable, canary, formatCanarySteps(steps))
+	if awsCfg, err := app.awsConfig(ctx, app.Region); err == nil {
+		app.journal(ctx, awsCfg, journalEntry{Action: "canary upgrade", Function: functionName, Alias: alias, AliasVersion: stable, Old: snapshotConfig(cfg)}, report)
+	}
+	return report, app.smokeTest(ctx, clientLamb, functionName, alias, []string{functionName}, report)
+}
+
+// canaryRollbackAllowed refuses a canary on a runtime that is blocked for update, a failed check
+// could not put $LATEST back on it
+func canaryRollbackAllowed(runtime types.Runtime, now time.Time) error {
+	if phase, schedule := runtimePhase(string(runtime), now, 0); phase == phaseBlockUpdate {
+		return fmt.Errorf("updates to %s are blocked since %s, a failed canary could not roll back to it, upgrade without a Canary Alias instead", runtime, schedule.blockUpdate.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// canaryHealthCheck fails a window without invocations through the alias unless the smoke invocation passed
+func (app *applicationMain) canaryHealthCheck(ctx context.Context, clientLamb *lambda.Client, functionName string, canary string, since time.Time, report *lambdaReport, progress func(string)) error {
+	if app.CanaryPayload != "" {
+		resp, err := clientLamb.Invoke(ctx, &lambda.InvokeInput{
+			FunctionName: aws.String(functionName),
+			Qualifier:    aws.String(canary),
+			Payload:      []byte(app.CanaryPayload),
+		})
+		if err != nil {
+			return fmt.Errorf("smoke invocation failed: %v", err)
+		}
+		if resp.FunctionError != nil {
+			return fmt.Errorf("smoke invocation returned %s: %s", aws.ToString(resp.FunctionError), resp.Payload)
+		}
+	}
+
+	if app.CanaryErrorRate > 0 {
+		//the metrics of invocations through the alias that landed on the canary version
+		dimensions := []metricDimension{
+			{"FunctionName", functionName},
+			{"Resource", functionName + ":" + app.CanaryAlias},
+			{"ExecutedVersion", canary},
+		}
+		invocations, err := app.lambdaMetricSum(ctx, "Invocations", dimensions, since, time.Now())
+		if err != nil {
+			return err
+		}
+		errorCount, err := app.lambdaMetricSum(ctx, "Errors", dimensions, since, time.Now())
+		if err != nil {
+			return err
+		}
+		if invocations == 0 {
+			if app.CanaryPayload == "" {
+				return fmt.Errorf("no invocations through %s reached version %s in the window, the error rate cannot be checked", app.CanaryAlias, canary)
+			}
+			progress(fmt.Sprintf("%s: no invocations through %s in the window, only the smoke invocation was checked", functionName, app.CanaryAlias))
+			report.warn("no invocations through %s reached version %s after %s, only the smoke invocation was checked", app.CanaryAlias, canary, since.Format("15:04:05"))
+			return nil
+		}
+		if rate := errorCount / invocations * 100; rate > app.CanaryErrorRate {
+			return fmt.Errorf("error rate %.1f%% (%.0f of %.0f invocations) is above %.1f%%", rate, errorCount, invocations, app.CanaryErrorRate)
+		}
+	}
+	return nil
+}
+
+// canaryRollback leaves the canary version in place for debugging
+func (app *applicationMain) canaryRollback(ctx context.Context, clientLamb *lambda.Client, functionName string, stable string, runtime types.Runtime, report *lambdaReport, progress func(string), cause error) error {
+	progress(fmt.Sprintf("%s: %v, rolling back", functionName, cause))
+	_, err := clientLamb.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(functionName),
+		Name:            aws.String(app.CanaryAlias),
+		FunctionVersion: aws.String(stable),
+		RoutingConfig:   &types.AliasRoutingConfiguration{AdditionalVersionWeights: map[string]float64{}},
+	})
+	if err != nil {
+		return fmt.Errorf("%v\nrollback of alias %s failed, check it by hand:\n%v", cause, app.CanaryAlias, err)
+	}
+	if err = app.setRuntime(ctx, clientLamb, functionName, runtime); err != nil {
+		return fmt.Errorf("%v\n$LATEST could not be put back on %s:\n%v", cause, runtime, err)
+	}
+	progress(fmt.Sprintf("%s: rolled back, %s on version %s and $LATEST on %s", functionName, app.CanaryAlias, stable, runtime))
+	report.note("rolled back, alias %s on version %s and $LATEST on %s", app.CanaryAlias, stable, runtime)
+	return cause
+}
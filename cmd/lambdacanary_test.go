@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func TestCanaryRollbackAllowed(t *testing.T) {
+	tests := []struct {
+		runtime types.Runtime
+		now     string
+		wantErr bool
+	}{
+		{types.RuntimePython39, "2026-05-31", false},
+		{types.RuntimePython39, "2026-06-30", false},
+		{types.RuntimePython39, "2026-07-01", true},
+		{types.RuntimeNodejs12x, "2026-01-01", true},
+		{types.RuntimeNodejs22x, "2026-10-18", false},
+		{types.Runtime(""), "2026-10-18", false},
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse("2006-01-02", tt.now)
+		if err := canaryRollbackAllowed(tt.runtime, now); (err != nil) != tt.wantErr {
+			t.Errorf("canaryRollbackAllowed(%s, %s) = %v, wantErr %v", tt.runtime, tt.now, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// cloneJob reads the source through src and writes the clone through dst
+type cloneJob struct {
+	app           *applicationMain
+	ctx           context.Context
+	src           *lambda.Client
+	dst           *lambda.Client
+	source        *lambda.GetFunctionOutput
+	functionName  string
+	newName       string
+	upgrade2      bool
+	mappingsOff   bool
+	sourceRegion  string
+	targetRegion  string
+	sourceAccount string
+	targetAccount string
+	qualifiers    map[string]string
+	translated    map[string]bool
+	undo          []cloneUndo
+	syncing       bool
+	previous      *types.FunctionConfiguration
+	staged        []string
+	layers        map[string]string
+	planning      bool
+	callerArn     string
+	report        *lambdaReport
+}
+
+func (app *applicationMain) newCloneJob(functionName string, functionNameNew string, upgrade2 bool, mappingsOff bool) (*cloneJob, error) {
+	j := &cloneJob{
+		app:          app,
+		ctx:          context.Background(),
+		functionName: functionName,
+		newName:      functionNameNew,
+		upgrade2:     upgrade2,
+		mappingsOff:  mappingsOff,
+		sourceRegion: app.Region,
+		targetRegion: app.targetRegion(),
+		qualifiers:   map[string]string{},
+		translated:   map[string]bool{},
+		layers:       map[string]string{},
+		report:       newLambdaReport(fmt.Sprintf("%s -> %s", functionName, functionNameNew)),
+	}
+
+	//create lambda clients
+	err := j.resolveAccounts()
+	if err != nil {
+		return j, err
+	}
+	if j.crossRegion() || j.crossAccount() {
+		j.report.title = fmt.Sprintf("%s -> %s (%s)", functionName, functionNameNew, j.targetLabel())
+	}
+	j.src, err = app.createLambdaClient()
+	if err != nil {
+		return j, fmt.Errorf("failed to create Lambda connection:\n%v", err)
+	}
+	j.dst, err = app.createTargetLambdaClient()
+	if err != nil {
+		return j, fmt.Errorf("failed to create Lambda connection to %s:\n%v", j.targetLabel(), err)
+	}
+	return j, nil
+}
+
+func (app *applicationMain) cloneLambda(functionName string, functionNameNew string, upgrade2 bool, mappingsOff bool) (*lambdaReport, error) {
+	j, err := app.newCloneJob(functionName, functionNameNew, upgrade2, mappingsOff)
+	if err != nil {
+		return j.report, err
+	}
+
+	err = j.run()
+	j.cleanupStaging()
+	if err != nil {
+		if app.KeepPartialClone && !j.syncing {
+			j.report.warn("the partial clone was kept, delete %s before cloning again", functionNameNew)
+		} else if !app.KeepPartialClone {
+			//a synced clone only loses what this run added to it
+			j.rollback()
+		}
+	}
+	if err == nil {
+		j.journal()
+		err = app.smokeTest(j.ctx, j.dst, j.newName, "", []string{j.newName, j.functionName}, j.report)
+	}
+	return j.report, err
+}
+
+func (j *cloneJob) journal() {
+	cfg, err := j.app.targetAwsConfig(j.ctx, j.targetRegion)
+	if err != nil {
+		return
+	}
+	entry := journalEntry{Action: "clone", Function: j.newName, RoleArn: j.app.TargetRoleArn}
+	if j.previous != nil {
+		entry.Action = "sync"
+		entry.Old = snapshotConfig(j.previous)
+	}
+	j.app.journal(j.ctx, cfg, entry, j.report)
+}
+
+func (j *cloneJob) existingClone() (*lambda.GetFunctionOutput, error) {
+	existing, err := j.dst.GetFunction(j.ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(j.newName),
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for an existing function %s:\n%v", j.newName, err)
+	}
+	return existing, nil
+}
+
+func (j *cloneJob) run() error {
+	ctx := j.ctx
+
+	//get the lambda function
+	result, err := j.src.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get function details:\n%v", err)
+	}
+	j.source = result
+
+	//an upgrade the layers do not support is stopped before anything is created
+	if err = j.checkUpgradeLayers(); err != nil {
+		return err
+	}
+
+	//an existing clone is brought up to date instead of created
+	existing, err := j.existingClone()
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if !j.app.SyncExisting {
+			return fmt.Errorf("function %s already exists, turn on Sync Existing Clones to update it", j.newName)
+		}
+		return j.sync(existing)
+	}
+
+	//zip package or container image
+	code, err := j.cloneCode(result)
+	if err != nil {
+		return err
+	}
+	if j.upgrade2 && result.Configuration.PackageType == types.PackageTypeImage {
+		j.report.warn("container image functions have no managed runtime, upgrade skipped")
+	}
+
+	//create the new lambda
+	createInput := j.newFunctionInput(result.Configuration)
+	createInput.Code = code
+	//with the full version history the clone's versions are published one by one later
+	createInput.Publish = !j.app.CloneVersionHistory
+
+	//code signing is attached to the function outside of its configuration
+	signingResp, err := j.src.GetFunctionCodeSigningConfig(ctx, &lambda.GetFunctionCodeSigningConfigInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	if err == nil && signingResp.CodeSigningConfigArn != nil {
+		if arn, ok := j.translateId("code signing config", aws.ToString(signingResp.CodeSigningConfigArn)); ok {
+			createInput.CodeSigningConfigArn = aws.String(arn)
+		}
+	}
+
+	newLamb, err := j.dst.CreateFunction(ctx, createInput)
+	if err != nil {
+		return fmt.Errorf("failed to create a new lambda function:\n%v", err)
+	}
+	j.createdFunction()
+
+	//nothing else can be attached while the function is still Pending
+	if err = j.app.waitForFunctionActive(ctx, j.dst, j.newName); err != nil {
+		return err
+	}
+
+	//runtime management and recursive loop detection
+	err = j.cloneRuntimeSettings(createInput.Runtime)
+	if err != nil {
+		return err
+	}
+
+	//copy tags, with the tag rules and provenance tags applied
+	var sourceTags map[string]string
+	tagResp, err := j.src.ListTags(ctx, &lambda.ListTagsInput{
+		Resource: result.Configuration.FunctionArn,
+	})
+	if err == nil {
+		sourceTags = tagResp.Tags
+	}
+	if tags := j.cloneTags(sourceTags); len(tags) > 0 {
+		_, err = j.dst.TagResource(ctx, &lambda.TagResourceInput{
+			Resource: newLamb.FunctionArn,
+			Tags:     tags,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add tags to new Lambda function: %v", err)
+		}
+	}
+
+	// Copy Concurrency (if set).
+	concurrencyResp, err := j.src.GetFunctionConcurrency(ctx, &lambda.GetFunctionConcurrencyInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	if err == nil && concurrencyResp.ReservedConcurrentExecutions != nil {
+		_, err = j.dst.PutFunctionConcurrency(ctx, &lambda.PutFunctionConcurrencyInput{
+			FunctionName:                 aws.String(j.newName),
+			ReservedConcurrentExecutions: concurrencyResp.ReservedConcurrentExecutions,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set concurrency on new Lambda function: %v", err)
+		}
+	}
+
+	//versions and aliases, publishing clone versions that line up with the source ones
+	if err = j.cloneAliases(); err != nil {
+		return err
+	}
+
+	//event source mappings (after aliases so alias bound mappings have a target)
+	if err = j.cloneEventSourceMappings(); err != nil {
+		return err
+	}
+
+	//function urls, async invoke settings and provisioned concurrency
+	if err = j.cloneFunctionUrls(); err != nil {
+		return err
+	}
+	if err = j.cloneEventInvokeConfigs(); err != nil {
+		return err
+	}
+	if err = j.cloneProvisionedConcurrency(); err != nil {
+		return err
+	}
+
+	//resource policies (after aliases so alias qualified statements have a target)
+	return j.clonePolicy()
+}
+
+func (j *cloneJob) cloneCode(source *lambda.GetFunctionOutput) (*types.FunctionCode, error) {
+	if source.Configuration.PackageType == types.PackageTypeImage {
+		if source.Code == nil || source.Code.ImageUri == nil {
+			return nil, fmt.Errorf("no image uri found for the function")
+		}
+		imageUri := aws.ToString(source.Code.ImageUri)
+		if j.app.ImageRepository != "" {
+			copied, err := j.app.copyEcrImage(j.ctx, imageUri, j.app.ImageRepository)
+			if err != nil {
+				return nil, fmt.Errorf("failed to copy image to %s:\n%v", j.app.ImageRepository, err)
+			}
+			j.report.note("image %s copied to %s", imageUri, copied)
+			imageUri = copied
+		} else if j.crossRegion() || j.crossAccount() {
+			return nil, fmt.Errorf("image %s cannot be used from %s, set an Image Repository there", imageUri, j.targetLabel())
+		}
+		return &types.FunctionCode{ImageUri: aws.String(imageUri)}, nil
+	}
+
+	//download the lambda Zip file
+	if source.Code == nil || source.Code.Location == nil {
+		return nil, fmt.Errorf("no code location found for the function")
+	}
+	codeSha := aws.ToString(source.Configuration.CodeSha256)
+	fileName, size, err := downloadFunctionCode(*source.Code.Location, codeSha)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(fileName)
+	return j.zipCode(fileName, size, codeSha, j.newName)
+}
+
+// newFunctionInput leaves Code and Publish to the caller, settings that cannot follow to the target are dropped
+func (j *cloneJob) newFunctionInput(cfg *types.FunctionConfiguration) *lambda.CreateFunctionInput {
+	report := j.report
+
+	//layers
+	var layerArns []string
+	if cfg.Layers != nil {
+		for _, layer := range cfg.Layers {
+			if layer.Arn != nil {
+				if arn, ok := j.translateLayer(*layer.Arn); ok {
+					layerArns = append(layerArns, arn)
+				}
+			}
+		}
+	}
+
+	//environment variables, with the rewrite rules applied
+	var env *types.Environment
+	if cfg.Environment != nil || len(j.app.EnvOverrides) > 0 {
+		var vars map[string]string
+		if cfg.Environment != nil {
+			vars = cfg.Environment.Variables
+		}
+		vars, problems := j.app.rewriteEnvironment(vars)
+		for _, problem := range problems {
+			report.warn("%s", problem)
+		}
+		env = &types.Environment{
+			Variables: vars,
+		}
+	}
+
+	//vpc config, every subnet and security group needs a counterpart in the target region
+	var vpcConfig *types.VpcConfig
+	if cfg.VpcConfig != nil && len(cfg.VpcConfig.SubnetIds) > 0 {
+		subnets, subnetsOk := j.translateIds("subnet", cfg.VpcConfig.SubnetIds)
+		groups, groupsOk := j.translateIds("security group", cfg.VpcConfig.SecurityGroupIds)
+		if subnetsOk && groupsOk {
+			vpcConfig = &types.VpcConfig{
+				SecurityGroupIds:        groups,
+				SubnetIds:               subnets,
+				Ipv6AllowedForDualStack: cfg.VpcConfig.Ipv6AllowedForDualStack,
+			}
+		} else {
+			report.warn("clone created without VPC access because the VPC %s could not be translated", aws.ToString(cfg.VpcConfig.VpcId))
+		}
+	}
+
+	//runtime selection
+	runtimeToUse := cfg.Runtime
+	if j.upgrade2 {
+		upgraded, err := j.app.upgradeRuntime(j.functionName, cfg.Runtime)
+		if err != nil && cfg.PackageType != types.PackageTypeImage {
+			report.warn("runtime left on %s: %v", cfg.Runtime, err)
+		}
+		runtimeToUse = upgraded
+	}
+
+	//container images carry their own runtime and entrypoint
+	handler := cfg.Handler
+	var imageConfig *types.ImageConfig
+	if cfg.PackageType == types.PackageTypeImage {
+		runtimeToUse = ""
+		handler = nil
+		if cfg.ImageConfigResponse != nil {
+			imageConfig = cfg.ImageConfigResponse.ImageConfig
+		}
+	}
+
+	//snapstart only exists on some runtimes
+	var snapStart *types.SnapStart
+	if cfg.SnapStart != nil && cfg.SnapStart.ApplyOn != types.SnapStartApplyOnNone && cfg.SnapStart.ApplyOn != "" {
+		if supportsSnapStart(runtimeToUse) {
+			snapStart = &types.SnapStart{ApplyOn: cfg.SnapStart.ApplyOn}
+		} else {
+			report.warn("SnapStart (%s) is not available on %s and was left off", cfg.SnapStart.ApplyOn, runtimeToUse)
+		}
+	}
+
+	//the default log group is named after the function and becomes the clone's own, a custom log
+	//group is shared with the source function
+	var loggingConfig *types.LoggingConfig
+	if cfg.LoggingConfig != nil {
+		logging := *cfg.LoggingConfig
+		if aws.ToString(logging.LogGroup) == "/aws/lambda/"+aws.ToString(cfg.FunctionName) {
+			logging.LogGroup = aws.String("/aws/lambda/" + j.newName)
+		} else if logging.LogGroup != nil && !j.crossRegion() && !j.crossAccount() {
+			report.warn("clone writes to the same log group as the source: %s", aws.ToString(logging.LogGroup))
+		}
+		loggingConfig = &logging
+	}
+
+	//execution role, account bound
+	role := cfg.Role
+	if cfg.Role != nil {
+		if arn, ok := j.translateArn("execution role", *cfg.Role); ok {
+			role = aws.String(arn)
+		}
+	}
+
+	//region and account bound references
+	var kmsKey *string
+	if cfg.KMSKeyArn != nil {
+		if arn, ok := j.translateArn("KMS key", *cfg.KMSKeyArn); ok {
+			kmsKey = aws.String(arn)
+		}
+	}
+	var deadLetter *types.DeadLetterConfig
+	if cfg.DeadLetterConfig != nil && cfg.DeadLetterConfig.TargetArn != nil {
+		if arn, ok := j.translateArn("dead letter queue", *cfg.DeadLetterConfig.TargetArn); ok {
+			deadLetter = &types.DeadLetterConfig{TargetArn: aws.String(arn)}
+		}
+	}
+	var fileSystems []types.FileSystemConfig
+	for _, fs := range cfg.FileSystemConfigs {
+		if arn, ok := j.translateId("file system", aws.ToString(fs.Arn)); ok {
+			fileSystems = append(fileSystems, types.FileSystemConfig{Arn: aws.String(arn), LocalMountPath: fs.LocalMountPath})
+		}
+	}
+
+	return &lambda.CreateFunctionInput{
+		FunctionName:      aws.String(j.newName),
+		Runtime:           runtimeToUse,
+		Role:              role,
+		Handler:           handler,
+		ImageConfig:       imageConfig,
+		Timeout:           cfg.Timeout,
+		MemorySize:        cfg.MemorySize,
+		Environment:       env,
+		Layers:            layerArns,
+		TracingConfig:     (*types.TracingConfig)(cfg.TracingConfig),
+		Architectures:     cfg.Architectures,
+		PackageType:       cfg.PackageType,
+		Description:       cfg.Description,
+		VpcConfig:         vpcConfig,
+		DeadLetterConfig:  deadLetter,
+		FileSystemConfigs: fileSystems,
+		EphemeralStorage:  cfg.EphemeralStorage,
+		KMSKeyArn:         kmsKey,
+		SnapStart:         snapStart,
+		LoggingConfig:     loggingConfig,
+	}
+}
+
+// configUpdateInput sends missing settings empty, a nil one would leave the clone's own in place
+func configUpdateInput(in *lambda.CreateFunctionInput) *lambda.UpdateFunctionConfigurationInput {
+	update := &lambda.UpdateFunctionConfigurationInput{
+		FunctionName:      in.FunctionName,
+		Runtime:           in.Runtime,
+		Role:              in.Role,
+		Handler:           in.Handler,
+		ImageConfig:       in.ImageConfig,
+		Timeout:           in.Timeout,
+		MemorySize:        in.MemorySize,
+		Environment:       in.Environment,
+		Layers:            in.Layers,
+		TracingConfig:     in.TracingConfig,
+		Description:       in.Description,
+		VpcConfig:         in.VpcConfig,
+		DeadLetterConfig:  in.DeadLetterConfig,
+		FileSystemConfigs: in.FileSystemConfigs,
+		EphemeralStorage:  in.EphemeralStorage,
+		KMSKeyArn:         in.KMSKeyArn,
+		SnapStart:         in.SnapStart,
+		LoggingConfig:     in.LoggingConfig,
+	}
+	//container images take no layers at all
+	if update.Layers == nil && in.PackageType != types.PackageTypeImage {
+		update.Layers = []string{}
+	}
+	if update.Environment == nil {
+		update.Environment = &types.Environment{Variables: map[string]string{}}
+	}
+	if update.VpcConfig == nil {
+		update.VpcConfig = &types.VpcConfig{SubnetIds: []string{}, SecurityGroupIds: []string{}}
+	}
+	if update.DeadLetterConfig == nil {
+		update.DeadLetterConfig = &types.DeadLetterConfig{TargetArn: aws.String("")}
+	}
+	if update.FileSystemConfigs == nil {
+		update.FileSystemConfigs = []types.FileSystemConfig{}
+	}
+	return update
+}
+
+func (j *cloneJob) checkUpgradeLayers() error {
+	cfg := j.source.Configuration
+	if !j.upgrade2 || cfg.PackageType == types.PackageTypeImage || len(cfg.Layers) == 0 {
+		return nil
+	}
+	runtime, err := j.app.upgradeRuntime(j.functionName, cfg.Runtime)
+	if err != nil || runtime == cfg.Runtime {
+		return nil
+	}
+	return j.app.checkLayers(j.ctx, j.src, cfg, runtime, j.report)
+}
+
+// cloneRuntimeSettings skips a pinned runtime version when the runtime changes
+func (j *cloneJob) cloneRuntimeSettings(runtimeNew types.Runtime) error {
+	ctx := j.ctx
+	cfg := j.source.Configuration
+
+	runtimeResp, err := j.src.GetRuntimeManagementConfig(ctx, &lambda.GetRuntimeManagementConfigInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	if err == nil && runtimeResp.UpdateRuntimeOn != "" && runtimeResp.UpdateRuntimeOn != types.UpdateRuntimeOnAuto {
+		input := &lambda.PutRuntimeManagementConfigInput{
+			FunctionName:    aws.String(j.newName),
+			UpdateRuntimeOn: runtimeResp.UpdateRuntimeOn,
+		}
+		if runtimeResp.UpdateRuntimeOn == types.UpdateRuntimeOnManual {
+			input.RuntimeVersionArn = runtimeResp.RuntimeVersionArn
+		}
+		if runtimeResp.UpdateRuntimeOn == types.UpdateRuntimeOnManual && (runtimeNew != cfg.Runtime || j.crossRegion()) {
+			j.report.warn("runtime version pinned to %s does not apply to the clone, clone left on automatic runtime updates", aws.ToString(runtimeResp.RuntimeVersionArn))
+		} else {
+			_, err = j.dst.PutRuntimeManagementConfig(ctx, input)
+			if err != nil {
+				return fmt.Errorf("failed to set runtime management on new Lambda function: %v", err)
+			}
+			j.report.note("runtime updates: %s", runtimeResp.UpdateRuntimeOn)
+		}
+	}
+
+	recursionResp, err := j.src.GetFunctionRecursionConfig(ctx, &lambda.GetFunctionRecursionConfigInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	if err == nil && recursionResp.RecursiveLoop != "" && recursionResp.RecursiveLoop != types.RecursiveLoopTerminate {
+		_, err = j.dst.PutFunctionRecursionConfig(ctx, &lambda.PutFunctionRecursionConfigInput{
+			FunctionName:  aws.String(j.newName),
+			RecursiveLoop: recursionResp.RecursiveLoop,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set recursive loop detection on new Lambda function: %v", err)
+		}
+		j.report.note("recursive loop: %s", recursionResp.RecursiveLoop)
+	}
+	return nil
+}
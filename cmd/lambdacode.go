@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	//CreateFunction and UpdateFunctionCode take zip files up to this size directly, larger ones go through S3
+	directUploadLimit = 50 * 1024 * 1024
+	downloadAttempts  = 3
+)
+
+// downloadClient cuts off stalled transfers, presigned urls only stay valid for minutes
+var downloadClient = &http.Client{Timeout: 10 * time.Minute}
+
+func httpGetWithRetry(url string) (*http.Response, error) {
+	var lastErr error
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt-1) * 2 * time.Second)
+		}
+		resp, err := downloadClient.Get(url)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode >= 500 {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("server returned %s", resp.Status)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("server returned %s", resp.Status)
+		}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("giving up after %d attempts: %v", downloadAttempts, lastErr)
+}
+
+// downloadFunctionCode checks the package against the base64 SHA-256 lambda reported, the caller removes the file
+func downloadFunctionCode(location string, codeSha256 string) (string, int64, error) {
+	var lastErr error
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt-1) * 2 * time.Second)
+		}
+		fileName, size, err := downloadToTempFile(location, codeSha256)
+		if err == nil {
+			return fileName, size, nil
+		}
+		lastErr = err
+	}
+	return "", 0, lastErr
+}
+
+func downloadToTempFile(location string, codeSha256 string) (string, int64, error) {
+	resp, err := downloadClient.Get(location)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to download code function:\n%v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("failed to download code function: %s", resp.Status)
+	}
+
+	file, err := os.CreateTemp("", "awscontrol-code-*.zip")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temp file for code:\n%v", err)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), resp.Body)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", 0, fmt.Errorf("failed to read code zip file content:\n%v", err)
+	}
+
+	if sum := base64.StdEncoding.EncodeToString(hash.Sum(nil)); codeSha256 != "" && sum != codeSha256 {
+		os.Remove(file.Name())
+		return "", 0, fmt.Errorf("downloaded code does not match CodeSha256 (got %s, expected %s)", sum, codeSha256)
+	}
+	return file.Name(), size, nil
+}
+
+// zipCode stages packages over directUploadLimit in the Staging Bucket until the clone is done
+func (j *cloneJob) zipCode(fileName string, size int64, codeSha256 string, name string) (*types.FunctionCode, error) {
+	if size <= directUploadLimit {
+		zipBytes, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read code zip file content:\n%v", err)
+		}
+		return &types.FunctionCode{ZipFile: zipBytes}, nil
+	}
+	if j.app.StagingBucket == "" {
+		return nil, fmt.Errorf("code package is %d MB, above the %d MB direct upload limit, set a Staging Bucket in %s", size/1024/1024, directUploadLimit/1024/1024, j.targetLabel())
+	}
+
+	cfg, err := j.app.targetAwsConfig(j.ctx, j.targetRegion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 connection:\n%v", err)
+	}
+	client := s3.NewFromConfig(cfg)
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read code zip file content:\n%v", err)
+	}
+	defer file.Close()
+
+	//base64 hashes carry '/' and '+', keep the key readable
+	key := fmt.Sprintf("awscontrol-staging/%s/%s.zip", name, strings.NewReplacer("/", "_", "+", "-", "=", "").Replace(codeSha256))
+	_, err = client.PutObject(j.ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(j.app.StagingBucket),
+		Key:           aws.String(key),
+		Body:          file,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage code in s3://%s/%s:\n%v", j.app.StagingBucket, key, err)
+	}
+	j.staged = append(j.staged, key)
+	j.report.note("code package (%d MB) staged through s3://%s", size/1024/1024, j.app.StagingBucket)
+	return &types.FunctionCode{S3Bucket: aws.String(j.app.StagingBucket), S3Key: aws.String(key)}, nil
+}
+
+func (j *cloneJob) cleanupStaging() {
+	if len(j.staged) == 0 {
+		return
+	}
+	cfg, err := j.app.targetAwsConfig(context.Background(), j.targetRegion)
+	if err != nil {
+		return
+	}
+	client := s3.NewFromConfig(cfg)
+	for _, key := range j.staged {
+		_, err := client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+			Bucket: aws.String(j.app.StagingBucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			j.report.warn("staged code s3://%s/%s could not be removed: %v", j.app.StagingBucket, key, err)
+		}
+	}
+	j.staged = nil
+}
+
+func updateCodeInput(functionName string, code *types.FunctionCode) *lambda.UpdateFunctionCodeInput {
+	return &lambda.UpdateFunctionCodeInput{
+		FunctionName: aws.String(functionName),
+		ZipFile:      code.ZipFile,
+		ImageUri:     code.ImageUri,
+		S3Bucket:     code.S3Bucket,
+		S3Key:        code.S3Key,
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+const defaultDeprecationWindowDays = 180
+
+// deprecationPhase is ordered worst last
+type deprecationPhase int
+
+const (
+	phaseSupported deprecationPhase = iota
+	phaseApproaching
+	phaseDeprecated
+	phaseBlockCreate
+	phaseBlockUpdate
+)
+
+var phaseLabels = map[deprecationPhase]string{
+	phaseApproaching: "Approaching deprecation",
+	phaseDeprecated:  "Deprecated",
+	phaseBlockCreate: "Blocked for create",
+	phaseBlockUpdate: "Blocked for update",
+}
+
+type deprecatedFunction struct {
+	name     string
+	runtime  string
+	phase    deprecationPhase
+	schedule runtimeSchedule
+}
+
+func (app *applicationMain) deprecationWindow() int {
+	if app.DeprecationWindowDays > 0 {
+		return app.DeprecationWindowDays
+	}
+	return defaultDeprecationWindowDays
+}
+
+func parseDays(value string) int {
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 0
+	}
+	return days
+}
+
+// runtimePhase counts runtimes without published dates (container images, newer runtimes) as supported
+func runtimePhase(runtime string, now time.Time, windowDays int) (deprecationPhase, runtimeSchedule) {
+	schedule, ok := runtimeSchedules[types.Runtime(runtime)]
+	switch {
+	case !ok:
+		return phaseSupported, schedule
+	case !now.Before(schedule.blockUpdate):
+		return phaseBlockUpdate, schedule
+	case !now.Before(schedule.blockCreate):
+		return phaseBlockCreate, schedule
+	case !now.Before(schedule.deprecated):
+		return phaseDeprecated, schedule
+	case schedule.deprecated.Sub(now) <= time.Duration(windowDays)*24*time.Hour:
+		return phaseApproaching, schedule
+	}
+	return phaseSupported, schedule
+}
+
+func (app *applicationMain) runtimePhaseLabel(runtime string) string {
+	phase, _ := runtimePhase(runtime, time.Now(), app.deprecationWindow())
+	return phaseLabels[phase]
+}
+
+func (f deprecatedFunction) describe(now time.Time) string {
+	switch f.phase {
+	case phaseApproaching:
+		days := int(f.schedule.deprecated.Sub(now).Hours()/24) + 1
+		return fmt.Sprintf("deprecated on %s (in %d days)", f.schedule.deprecated.Format("2006-01-02"), days)
+	case phaseDeprecated:
+		return fmt.Sprintf("deprecated since %s, creates blocked from %s", f.schedule.deprecated.Format("2006-01-02"), f.schedule.blockCreate.Format("2006-01-02"))
+	case phaseBlockCreate:
+		return fmt.Sprintf("creates blocked since %s, updates blocked from %s", f.schedule.blockCreate.Format("2006-01-02"), f.schedule.blockUpdate.Format("2006-01-02"))
+	case phaseBlockUpdate:
+		return fmt.Sprintf("updates blocked since %s", f.schedule.blockUpdate.Format("2006-01-02"))
+	}
+	return ""
+}
+
+// deprecationReport takes name, runtime pairs as listAllLambdaFunctions returns them
+func (app *applicationMain) deprecationReport(lambdas [][]string, now time.Time) []deprecatedFunction {
+	var functions []deprecatedFunction
+	for _, value := range lambdas {
+		phase, schedule := runtimePhase(value[1], now, app.deprecationWindow())
+		if phase == phaseSupported {
+			continue
+		}
+		functions = append(functions, deprecatedFunction{name: value[0], runtime: value[1], phase: phase, schedule: schedule})
+	}
+	sort.SliceStable(functions, func(a, b int) bool {
+		fa, fb := functions[a], functions[b]
+		if fa.phase != fb.phase {
+			return fa.phase > fb.phase
+		}
+		if !fa.schedule.deprecated.Equal(fb.schedule.deprecated) {
+			return fa.schedule.deprecated.Before(fb.schedule.deprecated)
+		}
+		return fa.name < fb.name
+	})
+	return functions
+}
+
+func (app *applicationMain) formatDeprecationReport(functions []deprecatedFunction, now time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Runtime deprecation report %s, region %s, approaching within %d days\n", now.Format("2006-01-02"), app.Region, app.deprecationWindow()))
+	if len(functions) == 0 {
+		sb.WriteString("\nNo functions on deprecated runtimes\n")
+		return sb.String()
+	}
+	phase := phaseSupported
+	for _, f := range functions {
+		if f.phase != phase {
+			phase = f.phase
+			sb.WriteString(fmt.Sprintf("\n%s (%d)\n", phaseLabels[phase], countPhase(functions, phase)))
+		}
+		sb.WriteString(fmt.Sprintf("  %s   %s, %s, upgrade %s\n", f.name, f.runtime, f.describe(now), app.upgradeLabel(f.name, f.runtime)))
+	}
+	return sb.String()
+}
+
+func countPhase(functions []deprecatedFunction, phase deprecationPhase) int {
+	count := 0
+	for _, f := range functions {
+		if f.phase == phase {
+			count++
+		}
+	}
+	return count
+}
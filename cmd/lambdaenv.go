@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+type envRegexRule struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+}
+
+func parseEnvKeys(value string) []string {
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// parseEnvRegexRules reads "pattern => replacement; pattern2 => replacement2", regular expressions
+// often hold commas and equal signs
+func parseEnvRegexRules(value string) []envRegexRule {
+	var rules []envRegexRule
+	for _, rule := range strings.Split(value, ";") {
+		pattern, replace, found := strings.Cut(rule, "=>")
+		if found && strings.TrimSpace(pattern) != "" {
+			rules = append(rules, envRegexRule{Pattern: strings.TrimSpace(pattern), Replace: strings.TrimSpace(replace)})
+		}
+	}
+	return rules
+}
+
+func formatEnvRegexRules(rules []envRegexRule) string {
+	var parts []string
+	for _, rule := range rules {
+		parts = append(parts, rule.Pattern+" => "+rule.Replace)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (app *applicationMain) hasEnvRewrites() bool {
+	return len(app.EnvDropKeys) > 0 || len(app.EnvReplace) > 0 || len(app.EnvRegexRewrites) > 0 || len(app.EnvOverrides) > 0
+}
+
+// rewriteEnvironment drops keys first, then replaces values, then applies overrides which may add keys
+func (app *applicationMain) rewriteEnvironment(vars map[string]string) (map[string]string, []string) {
+	if !app.hasEnvRewrites() {
+		return vars, nil
+	}
+	var problems []string
+	out := map[string]string{}
+	for k, v := range vars {
+		out[k] = v
+	}
+	for _, key := range app.EnvDropKeys {
+		delete(out, key)
+	}
+
+	//literal replacements in a fixed order so overlapping rules always give the same result
+	var literals []string
+	for from := range app.EnvReplace {
+		literals = append(literals, from)
+	}
+	sort.Strings(literals)
+	for k, v := range out {
+		for _, from := range literals {
+			v = strings.ReplaceAll(v, from, app.EnvReplace[from])
+		}
+		out[k] = v
+	}
+
+	for _, rule := range app.EnvRegexRewrites {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("environment rule %s skipped: %v", rule.Pattern, err))
+			continue
+		}
+		for k, v := range out {
+			out[k] = re.ReplaceAllString(v, rule.Replace)
+		}
+	}
+
+	for k, v := range app.EnvOverrides {
+		out[k] = v
+	}
+	return out, problems
+}
+
+func envDiff(before map[string]string, after map[string]string) []string {
+	keys := map[string]bool{}
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+	var sorted []string
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var lines []string
+	for _, k := range sorted {
+		old, hadOld := before[k]
+		now, hasNew := after[k]
+		switch {
+		case hadOld && !hasNew:
+			lines = append(lines, fmt.Sprintf("- %s (dropped)", k))
+		case !hadOld && hasNew:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", k, now))
+		case old != now:
+			lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", k, old, now))
+		}
+	}
+	return lines
+}
+
+func (app *applicationMain) envRewriteSummary(functionNames []string) string {
+	if !app.hasEnvRewrites() {
+		return ""
+	}
+	clientLamb, err := app.createLambdaClient()
+	if err != nil {
+		return fmt.Sprintf("Environment: failed to create Lambda connection:\n%v", err)
+	}
+
+	var sb strings.Builder
+	for _, name := range functionNames {
+		cfg, err := clientLamb.GetFunctionConfiguration(context.TODO(), &lambda.GetFunctionConfigurationInput{
+			FunctionName: aws.String(name),
+		})
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("Environment of %s could not be read: %v\n", name, err))
+			continue
+		}
+		var vars map[string]string
+		if cfg.Environment != nil {
+			vars = cfg.Environment.Variables
+		}
+		after, problems := app.rewriteEnvironment(vars)
+		lines := append(envDiff(vars, after), problems...)
+		if len(lines) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("Environment of %s:\n", name))
+		for _, line := range lines {
+			sb.WriteString("   " + line + "\n")
+		}
+	}
+	if sb.Len() == 0 {
+		return "Environment: no changes from the rewrite rules"
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRewriteEnvironment(t *testing.T) {
+	source := map[string]string{
+		"DB_HOST":     "orders-prod.cluster.example.com",
+		"QUEUE_URL":   "https://sqs/orders-prod",
+		"DEBUG_TOKEN": "secret",
+		"STAGE":       "prod",
+	}
+	tests := []struct {
+		name         string
+		app          applicationMain
+		want         map[string]string
+		wantProblems int
+	}{
+		{"no rules", applicationMain{}, source, 0},
+		{
+			name: "drop keys",
+			app:  applicationMain{EnvDropKeys: []string{"DEBUG_TOKEN", "MISSING"}},
+			want: map[string]string{"DB_HOST": "orders-prod.cluster.example.com", "QUEUE_URL": "https://sqs/orders-prod", "STAGE": "prod"},
+		},
+		{
+			name: "literal replacements apply in key order",
+			app:  applicationMain{EnvReplace: map[string]string{"orders-prod": "orders-staging", "prod": "dev"}},
+			want: map[string]string{"DB_HOST": "orders-staging.cluster.example.com", "QUEUE_URL": "https://sqs/orders-staging", "DEBUG_TOKEN": "secret", "STAGE": "dev"},
+		},
+		{
+			name: "regex with groups",
+			app:  applicationMain{EnvRegexRewrites: []envRegexRule{{Pattern: `^(.*)-prod(\..*)?$`, Replace: "${1}-staging${2}"}}},
+			want: map[string]string{"DB_HOST": "orders-staging.cluster.example.com", "QUEUE_URL": "https://sqs/orders-staging", "DEBUG_TOKEN": "secret", "STAGE": "prod"},
+		},
+		{
+			name:         "a bad regex is reported and skipped",
+			app:          applicationMain{EnvRegexRewrites: []envRegexRule{{Pattern: `(`, Replace: "x"}}},
+			want:         source,
+			wantProblems: 1,
+		},
+		{
+			name: "overrides win and may add keys",
+			app:  applicationMain{EnvDropKeys: []string{"STAGE"}, EnvReplace: map[string]string{"prod": "dev"}, EnvOverrides: map[string]string{"STAGE": "staging", "LOG_LEVEL": "debug"}},
+			want: map[string]string{"DB_HOST": "orders-dev.cluster.example.com", "QUEUE_URL": "https://sqs/orders-dev", "DEBUG_TOKEN": "secret", "STAGE": "staging", "LOG_LEVEL": "debug"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problems := tt.app.rewriteEnvironment(source)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rewriteEnvironment = %v, want %v", got, tt.want)
+			}
+			if len(problems) != tt.wantProblems {
+				t.Errorf("problems = %v, want %d", problems, tt.wantProblems)
+			}
+		})
+	}
+	if source["STAGE"] != "prod" || len(source) != 4 {
+		t.Errorf("the source variables were changed: %v", source)
+	}
+}
+
+func TestEnvDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before map[string]string
+		after  map[string]string
+		want   []string
+	}{
+		{"unchanged", map[string]string{"A": "1"}, map[string]string{"A": "1"}, nil},
+		{"both empty", nil, nil, nil},
+		{
+			name:   "added, changed and dropped in key order",
+			before: map[string]string{"B": "old", "C": "gone", "D": "same"},
+			after:  map[string]string{"A": "new", "B": "changed", "D": "same"},
+			want:   []string{"+ A: new", "~ B: old -> changed", "- C (dropped)"},
+		},
+		{"emptied value", map[string]string{"A": "1"}, map[string]string{"A": ""}, []string{"~ A: 1 -> "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := envDiff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("envDiff = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// cloneQualifierFor maps an unqualified arn to "", ok is false when the qualifier has no counterpart on the clone
+func cloneQualifierFor(arn string, qualifiers map[string]string) (string, bool) {
+	sourceQualifier := arnQualifier(arn)
+	if sourceQualifier == "" {
+		return "", true
+	}
+	qualifier, ok := qualifiers[sourceQualifier]
+	return qualifier, ok
+}
+
+func qualifierLabel(qualifier string) string {
+	if qualifier == "" {
+		return "function"
+	}
+	return qualifier
+}
+
+func (j *cloneJob) cloneFunctionUrls() error {
+	ctx := j.ctx
+	paginator := lambda.NewListFunctionUrlConfigsPaginator(j.src, &lambda.ListFunctionUrlConfigsInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list function urls: %v", err)
+		}
+		for _, url := range page.FunctionUrlConfigs {
+			qualifier, ok := cloneQualifierFor(aws.ToString(url.FunctionArn), j.qualifiers)
+			if !ok {
+				j.report.warn("function url %s not copied: qualifier %s has no counterpart on the clone", aws.ToString(url.FunctionUrl), arnQualifier(aws.ToString(url.FunctionArn)))
+				continue
+			}
+			input := &lambda.CreateFunctionUrlConfigInput{
+				FunctionName: aws.String(j.newName),
+				AuthType:     url.AuthType,
+				Cors:         url.Cors,
+				InvokeMode:   url.InvokeMode,
+			}
+			if qualifier != "" {
+				input.Qualifier = aws.String(qualifier)
+			}
+			newUrl, err := j.dst.CreateFunctionUrlConfig(ctx, input)
+			var conflict *types.ResourceConflictException
+			if err != nil && j.syncing && errors.As(err, &conflict) {
+				//the clone being synced already has this url, update it in place
+				_, err = j.dst.UpdateFunctionUrlConfig(ctx, &lambda.UpdateFunctionUrlConfigInput{
+					FunctionName: input.FunctionName,
+					Qualifier:    input.Qualifier,
+					AuthType:     input.AuthType,
+					Cors:         input.Cors,
+					InvokeMode:   input.InvokeMode,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to update function url on %s: %v", j.newName, err)
+				}
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to create function url on new Lambda function: %v", err)
+			}
+			j.report.note("function url (%s, %s) -> %s", qualifierLabel(qualifier), url.AuthType, aws.ToString(newUrl.FunctionUrl))
+		}
+	}
+	return nil
+}
+
+func (j *cloneJob) cloneEventInvokeConfigs() error {
+	ctx := j.ctx
+	paginator := lambda.NewListFunctionEventInvokeConfigsPaginator(j.src, &lambda.ListFunctionEventInvokeConfigsInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list event invoke configs: %v", err)
+		}
+		for _, invokeCfg := range page.FunctionEventInvokeConfigs {
+			qualifier, ok := cloneQualifierFor(aws.ToString(invokeCfg.FunctionArn), j.qualifiers)
+			if !ok {
+				j.report.warn("async invoke config of %s not copied: it has no counterpart on the clone", arnQualifier(aws.ToString(invokeCfg.FunctionArn)))
+				continue
+			}
+			input := &lambda.PutFunctionEventInvokeConfigInput{
+				FunctionName:             aws.String(j.newName),
+				DestinationConfig:        j.translateDestinations(invokeCfg.DestinationConfig),
+				MaximumEventAgeInSeconds: invokeCfg.MaximumEventAgeInSeconds,
+				MaximumRetryAttempts:     invokeCfg.MaximumRetryAttempts,
+			}
+			if qualifier != "" {
+				input.Qualifier = aws.String(qualifier)
+			}
+			_, err := j.dst.PutFunctionEventInvokeConfig(ctx, input)
+			if err != nil {
+				return fmt.Errorf("failed to set async invoke config on new Lambda function: %v", err)
+			}
+			j.report.note("async invoke config (%s) copied", qualifierLabel(qualifier))
+
+			if dest := invokeCfg.DestinationConfig; dest != nil {
+				if dest.OnSuccess != nil && pointsAtFunction(aws.ToString(dest.OnSuccess.Destination), j.functionName) {
+					j.report.warn("on-success destination of %s still points at the source function", qualifierLabel(qualifier))
+				}
+				if dest.OnFailure != nil && pointsAtFunction(aws.ToString(dest.OnFailure.Destination), j.functionName) {
+					j.report.warn("on-failure destination of %s still points at the source function", qualifierLabel(qualifier))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (j *cloneJob) translateDestinations(dest *types.DestinationConfig) *types.DestinationConfig {
+	if dest == nil {
+		return nil
+	}
+	out := &types.DestinationConfig{}
+	if dest.OnSuccess != nil && dest.OnSuccess.Destination != nil {
+		if arn, ok := j.translateArn("on-success destination", *dest.OnSuccess.Destination); ok {
+			out.OnSuccess = &types.OnSuccess{Destination: aws.String(arn)}
+		}
+	}
+	if dest.OnFailure != nil && dest.OnFailure.Destination != nil {
+		if arn, ok := j.translateArn("on-failure destination", *dest.OnFailure.Destination); ok {
+			out.OnFailure = &types.OnFailure{Destination: aws.String(arn)}
+		}
+	}
+	return out
+}
+
+func (j *cloneJob) cloneProvisionedConcurrency() error {
+	ctx := j.ctx
+	paginator := lambda.NewListProvisionedConcurrencyConfigsPaginator(j.src, &lambda.ListProvisionedConcurrencyConfigsInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list provisioned concurrency: %v", err)
+		}
+		for _, pc := range page.ProvisionedConcurrencyConfigs {
+			qualifier, ok := cloneQualifierFor(aws.ToString(pc.FunctionArn), j.qualifiers)
+			if !ok || qualifier == "" || qualifier == latestVersion {
+				j.report.warn("provisioned concurrency of %s not copied: it has no counterpart on the clone", arnQualifier(aws.ToString(pc.FunctionArn)))
+				continue
+			}
+			_, err := j.dst.PutProvisionedConcurrencyConfig(ctx, &lambda.PutProvisionedConcurrencyConfigInput{
+				FunctionName:                    aws.String(j.newName),
+				Qualifier:                       aws.String(qualifier),
+				ProvisionedConcurrentExecutions: pc.RequestedProvisionedConcurrentExecutions,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to set provisioned concurrency on new Lambda function: %v", err)
+			}
+			j.report.note("provisioned concurrency %d on %s", aws.ToInt32(pc.RequestedProvisionedConcurrentExecutions), qualifier)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// translateLayer only replicates layers the source account owns, vendor layers are referenced as they are
+// or by the same name in the target region
+func (j *cloneJob) translateLayer(arn string) (string, bool) {
+	if _, ok := j.app.ResourceRewrites[arn]; ok || (!j.crossRegion() && !j.crossAccount()) || layerAccount(arn) != j.sourceAccount {
+		return j.translateArn("layer", arn)
+	}
+	if to, ok := j.layers[arn]; ok {
+		return to, true
+	}
+	if j.planning {
+		j.report.note("+ PublishLayerVersion %s in %s (reused when an identical version is there)", layerName(arn), j.targetLabel())
+		return arn, true
+	}
+
+	to, err := j.replicateLayer(arn)
+	if err != nil {
+		j.report.warn("layer %s could not be replicated: %v", arn, err)
+		return j.translateArn("layer", arn)
+	}
+	j.layers[arn] = to
+	return to, true
+}
+
+func layerName(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 7 {
+		return arn
+	}
+	return parts[6]
+}
+
+func layerAccount(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 5 {
+		return ""
+	}
+	return parts[4]
+}
+
+// replicateLayer reuses an identical version already published where the clone lives
+func (j *cloneJob) replicateLayer(arn string) (string, error) {
+	ctx := j.ctx
+	source, err := j.src.GetLayerVersionByArn(ctx, &lambda.GetLayerVersionByArnInput{Arn: aws.String(arn)})
+	if err != nil {
+		return "", fmt.Errorf("failed to get layer version:\n%v", err)
+	}
+	if source.Content == nil || source.Content.Location == nil {
+		return "", fmt.Errorf("no content location found for the layer")
+	}
+	name := layerName(arn)
+	sha := aws.ToString(source.Content.CodeSha256)
+
+	existing, err := j.findLayerVersion(name, sha)
+	if err != nil {
+		return "", err
+	}
+	if existing != "" {
+		j.report.note("layer %s already replicated as %s", arn, existing)
+		return existing, nil
+	}
+
+	fileName, size, err := downloadFunctionCode(aws.ToString(source.Content.Location), sha)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(fileName)
+	code, err := j.zipCode(fileName, size, sha, "layer-"+name)
+	if err != nil {
+		return "", err
+	}
+
+	published, err := j.dst.PublishLayerVersion(ctx, &lambda.PublishLayerVersionInput{
+		LayerName: aws.String(name),
+		Content: &types.LayerVersionContentInput{
+			S3Bucket: code.S3Bucket,
+			S3Key:    code.S3Key,
+			ZipFile:  code.ZipFile,
+		},
+		CompatibleRuntimes:      source.CompatibleRuntimes,
+		CompatibleArchitectures: source.CompatibleArchitectures,
+		Description:             source.Description,
+		LicenseInfo:             source.LicenseInfo,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to publish layer %s in %s:\n%v", name, j.targetLabel(), err)
+	}
+	version := published.Version
+	j.created("layer version "+aws.ToString(published.LayerVersionArn), func(ctx context.Context) error {
+		_, err := j.dst.DeleteLayerVersion(ctx, &lambda.DeleteLayerVersionInput{LayerName: aws.String(name), VersionNumber: aws.Int64(version)})
+		return err
+	})
+	j.report.note("layer %s replicated as %s", arn, aws.ToString(published.LayerVersionArn))
+	return aws.ToString(published.LayerVersionArn), nil
+}
+
+func (j *cloneJob) findLayerVersion(name string, sha string) (string, error) {
+	paginator := lambda.NewListLayerVersionsPaginator(j.dst, &lambda.ListLayerVersionsInput{
+		LayerName: aws.String(name),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(j.ctx)
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			//a layer that was never published there has no versions to list
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to list versions of layer %s in %s:\n%v", name, j.targetLabel(), err)
+		}
+		for _, version := range page.LayerVersions {
+			out, err := j.dst.GetLayerVersionByArn(j.ctx, &lambda.GetLayerVersionByArnInput{Arn: version.LayerVersionArn})
+			if err != nil {
+				return "", fmt.Errorf("failed to read layer version %s:\n%v", aws.ToString(version.LayerVersionArn), err)
+			}
+			if out.Content != nil && aws.ToString(out.Content.CodeSha256) == sha {
+				return aws.ToString(version.LayerVersionArn), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+const (
+	layerCompatible   = "compatible"
+	layerIncompatible = "incompatible"
+	layerUnknown      = "unknown"
+)
+
+type layerVerdict struct {
+	arn     string
+	verdict string
+	reason  string
+}
+
+func (v layerVerdict) String() string {
+	if v.reason == "" {
+		return fmt.Sprintf("layer %s: %s", v.arn, v.verdict)
+	}
+	return fmt.Sprintf("layer %s: %s, %s", v.arn, v.verdict, v.reason)
+}
+
+// checkLayerCompatibility reads a layer without declarations or without GetLayerVersion permission as unknown
+func checkLayerCompatibility(ctx context.Context, clientLamb *lambda.Client, layers []types.Layer, runtime types.Runtime, architectures []types.Architecture) []layerVerdict {
+	if len(architectures) == 0 {
+		architectures = []types.Architecture{types.ArchitectureX8664}
+	}
+	var verdicts []layerVerdict
+	for _, layer := range layers {
+		arn := aws.ToString(layer.Arn)
+		meta, err := clientLamb.GetLayerVersionByArn(ctx, &lambda.GetLayerVersionByArnInput{Arn: layer.Arn})
+		if err != nil {
+			verdicts = append(verdicts, layerVerdict{arn, layerUnknown, fmt.Sprintf("metadata could not be read: %v", err)})
+			continue
+		}
+
+		verdict := layerVerdict{arn: arn, verdict: layerCompatible}
+		var reasons []string
+		if len(meta.CompatibleRuntimes) == 0 {
+			verdict.verdict = layerUnknown
+			reasons = append(reasons, "declares no compatible runtimes")
+		} else if !containsRuntime(meta.CompatibleRuntimes, runtime) {
+			verdict.verdict = layerIncompatible
+			reasons = append(reasons, fmt.Sprintf("declares %s, not %s", joinRuntimes(meta.CompatibleRuntimes), runtime))
+		}
+		for _, arch := range architectures {
+			if len(meta.CompatibleArchitectures) > 0 && !containsArchitecture(meta.CompatibleArchitectures, arch) {
+				verdict.verdict = layerIncompatible
+				reasons = append(reasons, fmt.Sprintf("built for %v, not %s", meta.CompatibleArchitectures, arch))
+			}
+		}
+		verdict.reason = strings.Join(reasons, ", ")
+		verdicts = append(verdicts, verdict)
+	}
+	return verdicts
+}
+
+func containsRuntime(runtimes []types.Runtime, runtime types.Runtime) bool {
+	for _, r := range runtimes {
+		if r == runtime {
+			return true
+		}
+	}
+	return false
+}
+
+func containsArchitecture(architectures []types.Architecture, arch types.Architecture) bool {
+	for _, a := range architectures {
+		if a == arch {
+			return true
+		}
+	}
+	return false
+}
+
+func joinRuntimes(runtimes []types.Runtime) string {
+	var names []string
+	for _, r := range runtimes {
+		names = append(names, string(r))
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkLayers never blocks on unknown layers
+func (app *applicationMain) checkLayers(ctx context.Context, clientLamb *lambda.Client, cfg *types.FunctionConfiguration, runtime types.Runtime, report *lambdaReport) error {
+	var incompatible []string
+	for _, verdict := range checkLayerCompatibility(ctx, clientLamb, cfg.Layers, runtime, cfg.Architectures) {
+		report.note("%s", verdict)
+		if verdict.verdict == layerIncompatible {
+			incompatible = append(incompatible, layerName(verdict.arn))
+		}
+	}
+	if len(incompatible) == 0 {
+		return nil
+	}
+	if app.AllowIncompatibleLayers {
+		report.warn("upgrading to %s despite incompatible layers %s, Allow Incompatible Layers is on", runtime, strings.Join(incompatible, ", "))
+		return nil
+	}
+	return fmt.Errorf("layers %s are not compatible with %s, upgrade blocked, turn on Allow Incompatible Layers to upgrade anyway", strings.Join(incompatible, ", "), runtime)
+}
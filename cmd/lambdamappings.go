@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func mappingInput(src types.EventSourceMappingConfiguration, functionTarget string, enabled bool) *lambda.CreateEventSourceMappingInput {
+	return &lambda.CreateEventSourceMappingInput{
+		FunctionName:                        aws.String(functionTarget),
+		Enabled:                             aws.Bool(enabled),
+		EventSourceArn:                      src.EventSourceArn,
+		AmazonManagedKafkaEventSourceConfig: src.AmazonManagedKafkaEventSourceConfig,
+		BatchSize:                           src.BatchSize,
+		BisectBatchOnFunctionError:          src.BisectBatchOnFunctionError,
+		DestinationConfig:                   src.DestinationConfig,
+		DocumentDBEventSourceConfig:         src.DocumentDBEventSourceConfig,
+		FilterCriteria:                      src.FilterCriteria,
+		FunctionResponseTypes:               src.FunctionResponseTypes,
+		KMSKeyArn:                           src.KMSKeyArn,
+		MaximumBatchingWindowInSeconds:      src.MaximumBatchingWindowInSeconds,
+		MaximumRecordAgeInSeconds:           src.MaximumRecordAgeInSeconds,
+		MaximumRetryAttempts:                src.MaximumRetryAttempts,
+		MetricsConfig:                       src.MetricsConfig,
+		ParallelizationFactor:               src.ParallelizationFactor,
+		ProvisionedPollerConfig:             src.ProvisionedPollerConfig,
+		Queues:                              src.Queues,
+		ScalingConfig:                       src.ScalingConfig,
+		SelfManagedEventSource:              src.SelfManagedEventSource,
+		SelfManagedKafkaEventSourceConfig:   src.SelfManagedKafkaEventSourceConfig,
+		SourceAccessConfigurations:          src.SourceAccessConfigurations,
+		StartingPosition:                    src.StartingPosition,
+		StartingPositionTimestamp:           src.StartingPositionTimestamp,
+		Topics:                              src.Topics,
+		TumblingWindowInSeconds:             src.TumblingWindowInSeconds,
+	}
+}
+
+func mappingSourceName(src types.EventSourceMappingConfiguration) string {
+	if src.EventSourceArn != nil {
+		return aws.ToString(src.EventSourceArn)
+	}
+	if src.SelfManagedEventSource != nil {
+		var endpoints []string
+		for _, list := range src.SelfManagedEventSource.Endpoints {
+			endpoints = append(endpoints, list...)
+		}
+		return strings.Join(endpoints, ",")
+	}
+	return aws.ToString(src.UUID)
+}
+
+// translateMapping is false when a region bound part of the mapping cannot follow it to the target
+func (j *cloneJob) translateMapping(input *lambda.CreateEventSourceMappingInput) bool {
+	if input.EventSourceArn != nil {
+		arn, ok := j.translateArn("event source", *input.EventSourceArn)
+		if !ok {
+			return false
+		}
+		input.EventSourceArn = aws.String(arn)
+	}
+	if input.KMSKeyArn != nil {
+		arn, ok := j.translateArn("KMS key", *input.KMSKeyArn)
+		if !ok {
+			return false
+		}
+		input.KMSKeyArn = aws.String(arn)
+	}
+	if input.DestinationConfig != nil && input.DestinationConfig.OnFailure != nil && input.DestinationConfig.OnFailure.Destination != nil {
+		arn, ok := j.translateArn("mapping failure destination", *input.DestinationConfig.OnFailure.Destination)
+		if !ok {
+			return false
+		}
+		input.DestinationConfig = &types.DestinationConfig{OnFailure: &types.OnFailure{Destination: aws.String(arn)}}
+	}
+
+	var access []types.SourceAccessConfiguration
+	for _, sac := range input.SourceAccessConfigurations {
+		uri := aws.ToString(sac.URI)
+		var to string
+		var ok bool
+		switch sac.Type {
+		case types.SourceAccessTypeVpcSubnet, types.SourceAccessTypeVpcSecurityGroup:
+			//uri is "subnet:subnet-123" or "security_group:sg-123"
+			prefix, id, _ := strings.Cut(uri, ":")
+			to, ok = j.translateId(strings.ReplaceAll(prefix, "_", " "), id)
+			to = prefix + ":" + to
+		default:
+			to, ok = j.translateArn(string(sac.Type), uri)
+		}
+		if !ok {
+			return false
+		}
+		access = append(access, types.SourceAccessConfiguration{Type: sac.Type, URI: aws.String(to)})
+	}
+	input.SourceAccessConfigurations = access
+	return true
+}
+
+func mappingState(input *lambda.CreateEventSourceMappingInput) string {
+	if aws.ToBool(input.Enabled) {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+type plannedMapping struct {
+	src        types.EventSourceMappingConfiguration
+	sourceName string
+	input      *lambda.CreateEventSourceMappingInput
+}
+
+func (j *cloneJob) mappingsToClone() ([]plannedMapping, error) {
+	var planned []plannedMapping
+	paginator := lambda.NewListEventSourceMappingsPaginator(j.src, &lambda.ListEventSourceMappingsInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(j.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list event source mappings: %v", err)
+		}
+
+		for _, src := range page.EventSourceMappings {
+			sourceName := mappingSourceName(src)
+
+			//mappings on an alias or version go to the same qualifier on the clone
+			functionTarget := j.newName
+			if sourceQualifier := arnQualifier(aws.ToString(src.FunctionArn)); sourceQualifier != "" {
+				qualifier, ok := j.qualifiers[sourceQualifier]
+				if !ok {
+					j.report.warn("event source mapping %s not copied: qualifier %s has no counterpart on the clone", sourceName, sourceQualifier)
+					continue
+				}
+				functionTarget = j.newName + ":" + qualifier
+			}
+
+			enabled := false
+			if src.State != nil && *src.State == "Enabled" {
+				enabled = true
+			}
+			if j.mappingsOff {
+				enabled = false
+			}
+
+			input := mappingInput(src, functionTarget, enabled)
+			if !j.translateMapping(input) {
+				j.report.warn("event source mapping %s not copied: it references resources missing in %s", sourceName, j.targetLabel())
+				continue
+			}
+			planned = append(planned, plannedMapping{src: src, sourceName: sourceName, input: input})
+		}
+	}
+	return planned, nil
+}
+
+func (j *cloneJob) cloneEventSourceMappings() error {
+	ctx := j.ctx
+	planned, err := j.mappingsToClone()
+	if err != nil {
+		return err
+	}
+	for _, m := range planned {
+		src, sourceName, input := m.src, m.sourceName, m.input
+		newMapping, err := j.dst.CreateEventSourceMapping(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to create event source mapping on new Lambda function: %v", err)
+		}
+		j.createdMapping(aws.ToString(newMapping.UUID), sourceName)
+
+		//mapping tags live on the mapping arn, not on the function
+		if src.EventSourceMappingArn != nil && newMapping.EventSourceMappingArn != nil {
+			tagResp, err := j.src.ListTags(ctx, &lambda.ListTagsInput{Resource: src.EventSourceMappingArn})
+			if err == nil && len(tagResp.Tags) > 0 {
+				_, err = j.dst.TagResource(ctx, &lambda.TagResourceInput{
+					Resource: newMapping.EventSourceMappingArn,
+					Tags:     tagResp.Tags,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to tag event source mapping on new Lambda function: %v", err)
+				}
+			}
+		}
+
+		j.report.note("event source mapping %s copied (%s)", sourceName, mappingState(input))
+
+		//a shared consumer group means the clone would take over offsets from the source
+		if (src.AmazonManagedKafkaEventSourceConfig != nil && src.AmazonManagedKafkaEventSourceConfig.ConsumerGroupId != nil) ||
+			(src.SelfManagedKafkaEventSourceConfig != nil && src.SelfManagedKafkaEventSourceConfig.ConsumerGroupId != nil) {
+			j.report.warn("event source mapping %s shares its Kafka consumer group with the source function", sourceName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+const maxFunctionNameLength = 64
+
+var functionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// nameData is what a Name Template can use, capture groups of the Name Pattern as {{.Group 1}} or {{.Named "env"}}
+type nameData struct {
+	Name    string
+	Runtime string
+	Date    string
+	groups  []string
+	named   map[string]string
+}
+
+func (d nameData) Group(i int) string {
+	if i < 0 || i >= len(d.groups) {
+		return ""
+	}
+	return d.groups[i]
+}
+
+func (d nameData) Named(name string) string {
+	return d.named[name]
+}
+
+func validateFunctionName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty name")
+	}
+	if len(name) > maxFunctionNameLength {
+		return fmt.Errorf("%d characters, the limit is %d", len(name), maxFunctionNameLength)
+	}
+	if !functionNamePattern.MatchString(name) {
+		return fmt.Errorf("only letters, numbers, '-' and '_' are allowed")
+	}
+	return nil
+}
+
+// cloneName takes the runtime the clone will run on
+func (app *applicationMain) cloneName(functionName string, runtime string) (string, error) {
+	if app.NameTemplate == "" {
+		if app.ReplaceExtension != "" && strings.Contains(functionName, app.ReplaceExtension) {
+			return strings.Replace(functionName, app.ReplaceExtension, app.FileNameExtension, -1), nil
+		}
+		return fmt.Sprintf("%s%s", functionName, app.FileNameExtension), nil
+	}
+
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(app.NameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("name template: %v", err)
+	}
+	//runtimes like python3.12 carry dots which names cannot
+	data := nameData{
+		Name:    functionName,
+		Runtime: strings.ReplaceAll(runtime, ".", ""),
+		Date:    time.Now().Format("20060102"),
+		named:   map[string]string{},
+	}
+	if app.NamePattern != "" {
+		re, err := regexp.Compile(app.NamePattern)
+		if err != nil {
+			return "", fmt.Errorf("name pattern: %v", err)
+		}
+		data.groups = re.FindStringSubmatch(functionName)
+		if data.groups == nil {
+			return "", fmt.Errorf("name pattern %s does not match", app.NamePattern)
+		}
+		for i, groupName := range re.SubexpNames() {
+			if groupName != "" {
+				data.named[groupName] = data.groups[i]
+			}
+		}
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("name template: %v", err)
+	}
+	return sb.String(), nil
+}
+
+// cloneNames checks every name before anything runs, with upgrade set {{.Runtime}} is the target runtime
+func (app *applicationMain) cloneNames(functionNames []string, runtimes map[string]string, upgrade bool) (map[string]string, string, bool) {
+	names := map[string]string{}
+	used := map[string]int{}
+	selected := map[string]bool{}
+	problems := map[string]string{}
+	for _, name := range functionNames {
+		selected[name] = true
+	}
+	for _, name := range functionNames {
+		runtime := runtimes[name]
+		if upgrade {
+			if target, err := app.upgradeRuntime(name, types.Runtime(runtime)); err == nil {
+				runtime = string(target)
+			}
+		}
+		newName, err := app.cloneName(name, runtime)
+		if err == nil {
+			err = validateFunctionName(newName)
+		}
+		if err != nil {
+			problems[name] = err.Error()
+		}
+		names[name] = newName
+		used[newName]++
+	}
+
+	ok := true
+	var sb strings.Builder
+	for _, name := range functionNames {
+		newName := names[name]
+		problem := problems[name]
+		switch {
+		case problem != "":
+		case used[newName] > 1:
+			problem = "duplicate name"
+		case selected[newName] || newName == name:
+			problem = "same name as a selected function"
+		}
+		if problem != "" {
+			ok = false
+			sb.WriteString(fmt.Sprintf("%s -> %s   !! %s\n", name, newName, problem))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%s -> %s\n", name, newName))
+	}
+	return names, strings.TrimRight(sb.String(), "\n"), ok
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloneName(t *testing.T) {
+	today := time.Now().Format("20060102")
+	tests := []struct {
+		name    string
+		app     applicationMain
+		source  string
+		runtime string
+		want    string
+		wantErr bool
+	}{
+		{"new text appended", applicationMain{FileNameExtension: "-staging"}, "orders", "python3.12", "orders-staging", false},
+		{"replace text", applicationMain{FileNameExtension: "-staging", ReplaceExtension: "-prod"}, "orders-prod-api", "python3.12", "orders-staging-api", false},
+		{"replace text missing appends", applicationMain{FileNameExtension: "-staging", ReplaceExtension: "-prod"}, "orders", "python3.12", "orders-staging", false},
+		{"runtime loses its dots", applicationMain{NameTemplate: "{{.Name}}-{{.Runtime}}"}, "orders", "python3.12", "orders-python312", false},
+		{"literal dots are kept", applicationMain{NameTemplate: "{{.Name}}.v2"}, "orders", "python3.12", "orders.v2", false},
+		{"date", applicationMain{NameTemplate: "{{.Name}}-{{.Date}}"}, "orders", "nodejs22.x", "orders-" + today, false},
+		{"numbered group", applicationMain{NameTemplate: "{{.Group 1}}-staging", NamePattern: "^(.*)-prod$"}, "orders-prod", "", "orders-staging", false},
+		{"named group", applicationMain{NameTemplate: `{{.Named "svc"}}-dr`, NamePattern: `^(?P<svc>\w+)-`}, "orders-prod", "", "orders-dr", false},
+		{"pattern does not match", applicationMain{NameTemplate: "{{.Group 1}}", NamePattern: "^(.*)-prod$"}, "orders", "", "", true},
+		{"bad template", applicationMain{NameTemplate: "{{.Name"}, "orders", "", "", true},
+		{"unknown field", applicationMain{NameTemplate: "{{.Nope}}"}, "orders", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.app.cloneName(tt.source, tt.runtime)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cloneName error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("cloneName = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloneNames(t *testing.T) {
+	runtimes := map[string]string{"orders": "python3.9", "billing": "nodejs18.x", "orders.v1": "python3.12"}
+	tests := []struct {
+		name      string
+		app       applicationMain
+		functions []string
+		upgrade   bool
+		want      map[string]string
+		problem   string
+	}{
+		{
+			name:      "source runtime on a plain clone",
+			app:       applicationMain{NameTemplate: "{{.Name}}-{{.Runtime}}"},
+			functions: []string{"orders", "billing"},
+			want:      map[string]string{"orders": "orders-python39", "billing": "billing-nodejs18x"},
+		},
+		{
+			name:      "target runtime on clone and upgrade",
+			app:       applicationMain{NameTemplate: "{{.Name}}-{{.Runtime}}"},
+			functions: []string{"orders", "billing"},
+			upgrade:   true,
+			want:      map[string]string{"orders": "orders-python313", "billing": "billing-nodejs22x"},
+		},
+		{
+			name:      "runtime pin names the clone",
+			app:       applicationMain{NameTemplate: "{{.Name}}-{{.Runtime}}", RuntimePins: map[string]string{"python": "python3.12"}},
+			functions: []string{"orders"},
+			upgrade:   true,
+			want:      map[string]string{"orders": "orders-python312"},
+		},
+		{
+			name:      "a dot in the source name is an error, not rewritten",
+			app:       applicationMain{FileNameExtension: "-staging"},
+			functions: []string{"orders.v1"},
+			want:      map[string]string{"orders.v1": "orders.v1-staging"},
+			problem:   "only letters",
+		},
+		{
+			name:      "too long",
+			app:       applicationMain{FileNameExtension: strings.Repeat("x", 60)},
+			functions: []string{"orders"},
+			want:      map[string]string{"orders": "orders" + strings.Repeat("x", 60)},
+			problem:   "the limit is 64",
+		},
+		{
+			name:      "two clones with one name",
+			app:       applicationMain{NameTemplate: "shared"},
+			functions: []string{"orders", "billing"},
+			want:      map[string]string{"orders": "shared", "billing": "shared"},
+			problem:   "duplicate name",
+		},
+		{
+			name:      "clone takes the name of a selected source",
+			app:       applicationMain{NameTemplate: "billing"},
+			functions: []string{"orders", "billing"},
+			want:      map[string]string{"orders": "billing", "billing": "billing"},
+			problem:   "duplicate name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, preview, ok := tt.app.cloneNames(tt.functions, runtimes, tt.upgrade)
+			if ok != (tt.problem == "") {
+				t.Fatalf("ok = %v, preview:\n%s", ok, preview)
+			}
+			for source, want := range tt.want {
+				if names[source] != want {
+					t.Errorf("name of %s = %q, want %q", source, names[source], want)
+				}
+			}
+			if tt.problem != "" && !strings.Contains(preview, tt.problem) {
+				t.Errorf("preview does not mention %q:\n%s", tt.problem, preview)
+			}
+		})
+	}
+}
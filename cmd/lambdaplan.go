@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// planClone changes nothing, it lists what cloneLambda would create with "+" and gives the clone's own warnings
+func (app *applicationMain) planClone(functionName string, functionNameNew string, upgrade2 bool, mappingsOff bool) (*lambdaReport, error) {
+	j, err := app.newCloneJob(functionName, functionNameNew, upgrade2, mappingsOff)
+	j.report.title = "Plan: " + j.report.title
+	j.planning = true
+	if err != nil {
+		return j.report, err
+	}
+	return j.report, j.plan()
+}
+
+func (j *cloneJob) plan() error {
+	ctx := j.ctx
+	report := j.report
+
+	result, err := j.src.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get function details:\n%v", err)
+	}
+	j.source = result
+	cfg := result.Configuration
+
+	//an existing clone would be synced, only its code and configuration can be compared up front
+	existing, err := j.existingClone()
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if !j.app.SyncExisting {
+			return fmt.Errorf("function %s already exists, turn on Sync Existing Clones to update it", j.newName)
+		}
+		report.note("~ sync existing function %s", j.newName)
+		if aws.ToString(cfg.CodeSha256) != aws.ToString(existing.Configuration.CodeSha256) {
+			report.note("~ code %s", aws.ToString(cfg.CodeSha256))
+		}
+		for _, change := range configChanges(existing.Configuration, j.newFunctionInput(cfg)) {
+			report.note("~ %s", change)
+		}
+		report.note("  tags, concurrency, aliases, event source mappings and permissions are reconciled when run")
+		return nil
+	}
+
+	//code
+	if cfg.PackageType == types.PackageTypeImage {
+		imageUri := ""
+		if result.Code != nil {
+			imageUri = aws.ToString(result.Code.ImageUri)
+		}
+		switch {
+		case j.app.ImageRepository != "":
+			report.note("+ copy image %s to %s", imageUri, j.app.ImageRepository)
+		case j.crossRegion() || j.crossAccount():
+			return fmt.Errorf("image %s cannot be used from %s, set an Image Repository there", imageUri, j.targetLabel())
+		default:
+			report.note("  image %s", imageUri)
+		}
+		if j.upgrade2 {
+			report.warn("container image functions have no managed runtime, upgrade skipped")
+		}
+	} else {
+		report.note("  download code package (%d bytes, sha %s)", cfg.CodeSize, aws.ToString(cfg.CodeSha256))
+	}
+
+	//function
+	if err := j.checkUpgradeLayers(); err != nil {
+		report.warn("%v", err)
+	}
+	in := j.newFunctionInput(cfg)
+	runtime := string(in.Runtime)
+	if in.Runtime != cfg.Runtime {
+		runtime = fmt.Sprintf("%s -> %s", cfg.Runtime, in.Runtime)
+	}
+	if runtime == "" {
+		runtime = "container image"
+	}
+	report.note("+ CreateFunction %s (%s, %d MB, %ds)", j.newName, runtime, aws.ToInt32(in.MemorySize), aws.ToInt32(in.Timeout))
+	report.note("    role %s", aws.ToString(in.Role))
+	for _, layer := range in.Layers {
+		report.note("    layer %s", layer)
+	}
+	if in.VpcConfig != nil {
+		report.note("    vpc subnets %s, security groups %s", strings.Join(in.VpcConfig.SubnetIds, ", "), strings.Join(in.VpcConfig.SecurityGroupIds, ", "))
+	}
+	if in.Environment != nil {
+		report.note("    %d environment variables", len(in.Environment.Variables))
+		var before map[string]string
+		if cfg.Environment != nil {
+			before = cfg.Environment.Variables
+		}
+		for _, line := range envDiff(before, in.Environment.Variables) {
+			report.note("      %s", line)
+		}
+	}
+	signingResp, err := j.src.GetFunctionCodeSigningConfig(ctx, &lambda.GetFunctionCodeSigningConfigInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	if err == nil && signingResp.CodeSigningConfigArn != nil {
+		if arn, ok := j.translateId("code signing config", aws.ToString(signingResp.CodeSigningConfigArn)); ok {
+			report.note("    code signing config %s", arn)
+		}
+	}
+
+	//tags and concurrency
+	var sourceTags map[string]string
+	tagResp, err := j.src.ListTags(ctx, &lambda.ListTagsInput{Resource: cfg.FunctionArn})
+	if err == nil {
+		sourceTags = tagResp.Tags
+	}
+	if tags := j.cloneTags(sourceTags); len(tags) > 0 {
+		report.note("+ TagResource %s", formatTags(tags))
+	}
+	concurrencyResp, err := j.src.GetFunctionConcurrency(ctx, &lambda.GetFunctionConcurrencyInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	if err == nil && concurrencyResp.ReservedConcurrentExecutions != nil {
+		report.note("+ PutFunctionConcurrency %d", aws.ToInt32(concurrencyResp.ReservedConcurrentExecutions))
+	}
+
+	//versions and aliases, clone version numbers are only known once published
+	j.qualifiers[latestVersion] = latestVersion
+	aliases, versions, err := j.aliasesToClone()
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		j.qualifiers[version] = "clone of " + version
+		report.note("+ PublishVersion from source version %s", version)
+	}
+	for _, alias := range aliases {
+		input, err := j.aliasInput(alias)
+		if err != nil {
+			return err
+		}
+		report.note("+ CreateAlias %s -> %s", aws.ToString(input.Name), aws.ToString(input.FunctionVersion))
+		j.qualifiers[aws.ToString(alias.Name)] = aws.ToString(alias.Name)
+	}
+
+	//event source mappings
+	mappings, err := j.mappingsToClone()
+	if err != nil {
+		return err
+	}
+	for _, m := range mappings {
+		report.note("+ CreateEventSourceMapping %s -> %s (%s)", m.sourceName, aws.ToString(m.input.FunctionName), mappingState(m.input))
+	}
+
+	//function urls, async invoke settings and provisioned concurrency
+	if err := j.planInvokeSettings(); err != nil {
+		return err
+	}
+
+	//resource policy
+	permissions, err := j.policyPermissions()
+	if err != nil {
+		return err
+	}
+	for _, input := range permissions {
+		report.note("+ AddPermission %s (%s for %s)", aws.ToString(input.StatementId), aws.ToString(input.Action), aws.ToString(input.Principal))
+	}
+	return nil
+}
+
+func (j *cloneJob) planInvokeSettings() error {
+	ctx := j.ctx
+	urls := lambda.NewListFunctionUrlConfigsPaginator(j.src, &lambda.ListFunctionUrlConfigsInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	for urls.HasMorePages() {
+		page, err := urls.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list function urls: %v", err)
+		}
+		for _, url := range page.FunctionUrlConfigs {
+			if qualifier, ok := cloneQualifierFor(aws.ToString(url.FunctionArn), j.qualifiers); ok {
+				j.report.note("+ CreateFunctionUrlConfig (%s, %s)", qualifierLabel(qualifier), url.AuthType)
+			}
+		}
+	}
+
+	invokeCfgs := lambda.NewListFunctionEventInvokeConfigsPaginator(j.src, &lambda.ListFunctionEventInvokeConfigsInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	for invokeCfgs.HasMorePages() {
+		page, err := invokeCfgs.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list event invoke configs: %v", err)
+		}
+		for _, invokeCfg := range page.FunctionEventInvokeConfigs {
+			if qualifier, ok := cloneQualifierFor(aws.ToString(invokeCfg.FunctionArn), j.qualifiers); ok {
+				j.translateDestinations(invokeCfg.DestinationConfig)
+				j.report.note("+ PutFunctionEventInvokeConfig (%s)", qualifierLabel(qualifier))
+			}
+		}
+	}
+
+	provisioned := lambda.NewListProvisionedConcurrencyConfigsPaginator(j.src, &lambda.ListProvisionedConcurrencyConfigsInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	for provisioned.HasMorePages() {
+		page, err := provisioned.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list provisioned concurrency: %v", err)
+		}
+		for _, pc := range page.ProvisionedConcurrencyConfigs {
+			if qualifier, ok := cloneQualifierFor(aws.ToString(pc.FunctionArn), j.qualifiers); ok && qualifier != "" && qualifier != latestVersion {
+				j.report.note("+ PutProvisionedConcurrencyConfig %d on %s", aws.ToInt32(pc.RequestedProvisionedConcurrentExecutions), qualifier)
+			}
+		}
+	}
+	return nil
+}
+
+func (app *applicationMain) planUpgrade(functionName string) (*lambdaReport, error) {
+	report := newLambdaReport("Plan: " + functionName)
+	clientLamb, err := app.createLambdaClient()
+	if err != nil {
+		return report, fmt.Errorf("failed to create Lambda connection:\n%v", err)
+	}
+	result, err := clientLamb.GetFunction(context.TODO(), &lambda.GetFunctionInput{
+		FunctionName: aws.String(functionName),
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to get function details:\n%v", err)
+	}
+	cfg := result.Configuration
+	if cfg.PackageType == types.PackageTypeImage {
+		report.warn("container image functions have no managed runtime, nothing to upgrade")
+		return report, nil
+	}
+	newRuntime, err := app.upgradeRuntime(functionName, cfg.Runtime)
+	if err != nil {
+		return report, err
+	}
+	if newRuntime == cfg.Runtime {
+		report.note("  runtime %s already current, no change", cfg.Runtime)
+		return report, nil
+	}
+	report.note("~ UpdateFunctionConfiguration runtime %s -> %s", cfg.Runtime, newRuntime)
+	if err = app.checkLayers(context.TODO(), clientLamb, cfg, newRuntime, report); err != nil {
+		report.warn("%v", err)
+	}
+	if app.CanaryAlias == "" {
+		return report, nil
+	}
+
+	//canary upgrades publish the new runtime and move the alias over step by step
+	if err = canaryRollbackAllowed(cfg.Runtime, time.Now()); err != nil {
+		return report, err
+	}
+	alias, err := clientLamb.GetAlias(context.TODO(), &lambda.GetAliasInput{
+		FunctionName: aws.String(functionName),
+		Name:         aws.String(app.CanaryAlias),
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to get alias %s:\n%v", app.CanaryAlias, err)
+	}
+	report.note("+ PublishVersion on %s", newRuntime)
+	report.note("~ UpdateAlias %s from version %s, %s%% then all traffic, %s between steps", app.CanaryAlias, aws.ToString(alias.FunctionVersion), formatCanarySteps(app.canarySteps()), app.canaryWait())
+	checks, err := app.canaryChecks()
+	if err != nil {
+		return report, err
+	}
+	report.note("    health check: %s", strings.Join(checks, " and "))
+	return report, nil
+}
+
+func formatTags(tags map[string]string) string {
+	var pairs []string
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func exportPlan(plan string) (string, error) {
+	return exportText("plan", plan)
+}
+
+// exportText writes <kind>-<date>.txt to the working directory
+func exportText(kind string, text string) (string, error) {
+	fileName := fmt.Sprintf("%s-%s.txt", kind, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(fileName, []byte(text), 0644); err != nil {
+		return "", fmt.Errorf("failed to export %s:\n%v", kind, err)
+	}
+	return fileName, nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+type lambdaPolicy struct {
+	Version   string                  `json:"Version"`
+	Id        string                  `json:"Id"`
+	Statement []lambdaPolicyStatement `json:"Statement"`
+}
+
+type lambdaPolicyStatement struct {
+	Sid       string                                `json:"Sid"`
+	Effect    string                                `json:"Effect"`
+	Principal json.RawMessage                       `json:"Principal"`
+	Action    json.RawMessage                       `json:"Action"`
+	Resource  json.RawMessage                       `json:"Resource"`
+	Condition map[string]map[string]json.RawMessage `json:"Condition"`
+}
+
+func policyStringList(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return nil, fmt.Errorf("unexpected value %s", string(raw))
+	}
+	return many, nil
+}
+
+// policyPrincipal turns a statement principal into the single value AddPermission accepts
+func policyPrincipal(raw json.RawMessage) (string, error) {
+	var wildcard string
+	if err := json.Unmarshal(raw, &wildcard); err == nil {
+		return wildcard, nil
+	}
+	var principals map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &principals); err != nil {
+		return "", fmt.Errorf("unreadable principal %s", string(raw))
+	}
+	if len(principals) != 1 {
+		return "", fmt.Errorf("more than one principal type")
+	}
+	for kind, value := range principals {
+		values, err := policyStringList(value)
+		if err != nil {
+			return "", err
+		}
+		if len(values) != 1 {
+			return "", fmt.Errorf("%d %s principals in one statement", len(values), kind)
+		}
+		return values[0], nil
+	}
+	return "", fmt.Errorf("empty principal")
+}
+
+// permissionsFromStatement refuses what AddPermission cannot express rather than replay it with looser access
+func permissionsFromStatement(stmt lambdaPolicyStatement, functionNameNew string, qualifiers map[string]string) ([]*lambda.AddPermissionInput, error) {
+	if !strings.EqualFold(stmt.Effect, "Allow") {
+		return nil, fmt.Errorf("effect %s is not supported", stmt.Effect)
+	}
+	if len(stmt.Principal) == 0 {
+		return nil, fmt.Errorf("no principal (NotPrincipal is not supported)")
+	}
+	principal, err := policyPrincipal(stmt.Principal)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := policyStringList(stmt.Action)
+	if err != nil || len(actions) == 0 {
+		return nil, fmt.Errorf("no usable action (NotAction is not supported)")
+	}
+
+	base := lambda.AddPermissionInput{
+		FunctionName: aws.String(functionNameNew),
+		Principal:    aws.String(principal),
+	}
+
+	//a resource with a trailing qualifier (function:name:alias) belongs to an alias or version
+	resources, err := policyStringList(stmt.Resource)
+	if err != nil || len(resources) != 1 {
+		return nil, fmt.Errorf("expected exactly one resource")
+	}
+	if sourceQualifier := arnQualifier(resources[0]); sourceQualifier != "" {
+		qualifier, ok := qualifiers[sourceQualifier]
+		if !ok {
+			return nil, fmt.Errorf("qualifier %s has no counterpart on the clone", sourceQualifier)
+		}
+		base.Qualifier = aws.String(qualifier)
+	}
+
+	for operator, values := range stmt.Condition {
+		for key, raw := range values {
+			list, err := policyStringList(raw)
+			if err != nil || len(list) != 1 {
+				return nil, fmt.Errorf("condition %s:%s must have exactly one value", operator, key)
+			}
+			value := list[0]
+			switch {
+			case strings.EqualFold(key, "AWS:SourceArn") && (strings.EqualFold(operator, "ArnLike") || strings.EqualFold(operator, "ArnEquals")):
+				base.SourceArn = aws.String(value)
+			case strings.EqualFold(key, "AWS:SourceAccount") && strings.EqualFold(operator, "StringEquals"):
+				base.SourceAccount = aws.String(value)
+			case strings.EqualFold(key, "aws:PrincipalOrgID") && strings.EqualFold(operator, "StringEquals"):
+				base.PrincipalOrgID = aws.String(value)
+			case strings.EqualFold(key, "lambda:FunctionUrlAuthType") && strings.EqualFold(operator, "StringEquals"):
+				base.FunctionUrlAuthType = types.FunctionUrlAuthType(value)
+			case strings.EqualFold(key, "lambda:EventSourceToken") && strings.EqualFold(operator, "StringEquals"):
+				base.EventSourceToken = aws.String(value)
+			default:
+				return nil, fmt.Errorf("condition %s:%s is not supported", operator, key)
+			}
+		}
+	}
+
+	var inputs []*lambda.AddPermissionInput
+	for idx, action := range actions {
+		input := base
+		input.Action = aws.String(action)
+		input.StatementId = aws.String(stmt.Sid)
+		if len(actions) > 1 {
+			input.StatementId = aws.String(fmt.Sprintf("%s-%d", stmt.Sid, idx+1))
+		}
+		inputs = append(inputs, &input)
+	}
+	return inputs, nil
+}
+
+// policyPermissions reads the policy of the function, each alias and each version, every qualifier has its own
+func (j *cloneJob) policyPermissions() ([]*lambda.AddPermissionInput, error) {
+	var permissions []*lambda.AddPermissionInput
+	sourceQualifiers := []string{""}
+	for qualifier := range j.qualifiers {
+		if qualifier != latestVersion {
+			sourceQualifiers = append(sourceQualifiers, qualifier)
+		}
+	}
+	sort.Strings(sourceQualifiers)
+
+	for _, qualifier := range sourceQualifiers {
+		input := &lambda.GetPolicyInput{FunctionName: aws.String(j.functionName)}
+		if qualifier != "" {
+			input.Qualifier = aws.String(qualifier)
+		}
+		policyResp, err := j.src.GetPolicy(j.ctx, input)
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			//no resource policy on this qualifier
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read resource policy of %s:\n%v", strings.TrimSuffix(j.functionName+":"+qualifier, ":"), err)
+		}
+		if policyResp.Policy == nil {
+			continue
+		}
+
+		var policy lambdaPolicy
+		if err := json.Unmarshal([]byte(*policyResp.Policy), &policy); err != nil {
+			j.report.warn("resource policy could not be read, no permissions copied: %v", err)
+			continue
+		}
+
+		for _, stmt := range policy.Statement {
+			inputs, err := permissionsFromStatement(stmt, j.newName, j.qualifiers)
+			if err != nil {
+				j.report.warn("permission %s not copied: %v", stmt.Sid, err)
+				continue
+			}
+			//principals and source accounts of the source account belong to the target account now
+			for _, input := range inputs {
+				input.Principal = aws.String(j.translateAccount("principal", aws.ToString(input.Principal)))
+				if input.SourceAccount != nil {
+					input.SourceAccount = aws.String(j.translateAccount("source account", *input.SourceAccount))
+				}
+			}
+
+			//the trigger of a statement has to exist where the clone lives
+			if inputs[0].SourceArn != nil {
+				arn, ok := j.translateArn("trigger", *inputs[0].SourceArn)
+				if !ok {
+					j.report.warn("permission %s not copied: its trigger is not available in %s", stmt.Sid, j.targetLabel())
+					continue
+				}
+				for _, input := range inputs {
+					input.SourceArn = aws.String(arn)
+				}
+			}
+			permissions = append(permissions, inputs...)
+		}
+	}
+	return permissions, nil
+}
+
+func (j *cloneJob) clonePolicy() error {
+	permissions, err := j.policyPermissions()
+	if err != nil {
+		return err
+	}
+	for _, input := range permissions {
+		_, err := j.dst.AddPermission(j.ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to add permission %s on new Lambda function: %v", aws.ToString(input.StatementId), err)
+		}
+		j.report.note("permission %s copied (%s for %s)", aws.ToString(input.StatementId), aws.ToString(input.Action), aws.ToString(input.Principal))
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestPolicyPrincipal(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"wildcard", `"*"`, "*", false},
+		{"service", `{"Service": "s3.amazonaws.com"}`, "s3.amazonaws.com", false},
+		{"account in a list", `{"AWS": ["arn:aws:iam::123456789012:root"]}`, "arn:aws:iam::123456789012:root", false},
+		{"two accounts", `{"AWS": ["123456789012", "210987654321"]}`, "", true},
+		{"two principal types", `{"AWS": "123456789012", "Service": "sns.amazonaws.com"}`, "", true},
+		{"empty object", `{}`, "", true},
+		{"empty list", `{"AWS": []}`, "", true},
+		{"number", `42`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policyPrincipal(json.RawMessage(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("policyPrincipal(%s) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("policyPrincipal(%s) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionsFromStatement(t *testing.T) {
+	const resource = `"arn:aws:lambda:us-east-1:123456789012:function:orders"`
+	qualifiers := map[string]string{"live": "live", "3": "1"}
+
+	tests := []struct {
+		name      string
+		statement string
+		wantErr   string
+		check     func(t *testing.T, sid string, principal string, qualifier string, sourceArn string, sourceAccount string, actions []string)
+	}{
+		{
+			name:      "service with source arn",
+			statement: `{"Sid": "s3", "Effect": "Allow", "Principal": {"Service": "s3.amazonaws.com"}, "Action": "lambda:InvokeFunction", "Resource": ` + resource + `, "Condition": {"ArnLike": {"AWS:SourceArn": "arn:aws:s3:::uploads"}, "StringEquals": {"AWS:SourceAccount": "123456789012"}}}`,
+			check: func(t *testing.T, sid string, principal string, qualifier string, sourceArn string, sourceAccount string, actions []string) {
+				if sid != "s3" || principal != "s3.amazonaws.com" || qualifier != "" || sourceArn != "arn:aws:s3:::uploads" || sourceAccount != "123456789012" {
+					t.Errorf("got sid %q principal %q qualifier %q source arn %q source account %q", sid, principal, qualifier, sourceArn, sourceAccount)
+				}
+			},
+		},
+		{
+			name:      "alias qualifier is mapped",
+			statement: `{"Sid": "a", "Effect": "Allow", "Principal": "*", "Action": "lambda:InvokeFunction", "Resource": "arn:aws:lambda:us-east-1:123456789012:function:orders:live", "Condition": {"StringEquals": {"aws:PrincipalOrgID": "o-abc"}}}`,
+			check: func(t *testing.T, sid string, principal string, qualifier string, sourceArn string, sourceAccount string, actions []string) {
+				if qualifier != "live" {
+					t.Errorf("qualifier = %q, want live", qualifier)
+				}
+			},
+		},
+		{
+			name:      "version qualifier is renumbered",
+			statement: `{"Sid": "v", "Effect": "Allow", "Principal": "*", "Action": "lambda:InvokeFunction", "Resource": "arn:aws:lambda:us-east-1:123456789012:function:orders:3"}`,
+			check: func(t *testing.T, sid string, principal string, qualifier string, sourceArn string, sourceAccount string, actions []string) {
+				if qualifier != "1" {
+					t.Errorf("qualifier = %q, want 1", qualifier)
+				}
+			},
+		},
+		{
+			name:      "one input per action",
+			statement: `{"Sid": "many", "Effect": "Allow", "Principal": {"AWS": "123456789012"}, "Action": ["lambda:InvokeFunction", "lambda:GetFunction"], "Resource": ` + resource + `}`,
+			check: func(t *testing.T, sid string, principal string, qualifier string, sourceArn string, sourceAccount string, actions []string) {
+				if len(actions) != 2 || actions[0] != "lambda:InvokeFunction" || actions[1] != "lambda:GetFunction" {
+					t.Errorf("actions = %v", actions)
+				}
+			},
+		},
+		{name: "deny", statement: `{"Sid": "d", "Effect": "Deny", "Principal": "*", "Action": "lambda:InvokeFunction", "Resource": ` + resource + `}`, wantErr: "effect"},
+		{name: "not principal", statement: `{"Sid": "n", "Effect": "Allow", "NotPrincipal": {"AWS": "123456789012"}, "Action": "lambda:InvokeFunction", "Resource": ` + resource + `}`, wantErr: "principal"},
+		{name: "not action", statement: `{"Sid": "n", "Effect": "Allow", "Principal": "*", "NotAction": "lambda:DeleteFunction", "Resource": ` + resource + `}`, wantErr: "action"},
+		{name: "two principals", statement: `{"Sid": "p", "Effect": "Allow", "Principal": {"AWS": ["111111111111", "222222222222"]}, "Action": "lambda:InvokeFunction", "Resource": ` + resource + `}`, wantErr: "principals"},
+		{name: "two resources", statement: `{"Sid": "r", "Effect": "Allow", "Principal": "*", "Action": "lambda:InvokeFunction", "Resource": [` + resource + `, "arn:aws:lambda:us-east-1:123456789012:function:orders:live"]}`, wantErr: "resource"},
+		{name: "unknown qualifier", statement: `{"Sid": "q", "Effect": "Allow", "Principal": "*", "Action": "lambda:InvokeFunction", "Resource": "arn:aws:lambda:us-east-1:123456789012:function:orders:beta"}`, wantErr: "qualifier"},
+		{name: "unsupported condition", statement: `{"Sid": "c", "Effect": "Allow", "Principal": "*", "Action": "lambda:InvokeFunction", "Resource": ` + resource + `, "Condition": {"IpAddress": {"aws:SourceIp": "10.0.0.0/8"}}}`, wantErr: "not supported"},
+		{name: "condition with two values", statement: `{"Sid": "c", "Effect": "Allow", "Principal": "*", "Action": "lambda:InvokeFunction", "Resource": ` + resource + `, "Condition": {"ArnLike": {"AWS:SourceArn": ["arn:aws:s3:::a", "arn:aws:s3:::b"]}}}`, wantErr: "exactly one value"},
+		{name: "source account with the wrong operator", statement: `{"Sid": "c", "Effect": "Allow", "Principal": "*", "Action": "lambda:InvokeFunction", "Resource": ` + resource + `, "Condition": {"StringLike": {"AWS:SourceAccount": "1234*"}}}`, wantErr: "not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stmt lambdaPolicyStatement
+			if err := json.Unmarshal([]byte(tt.statement), &stmt); err != nil {
+				t.Fatalf("bad statement in test: %v", err)
+			}
+			inputs, err := permissionsFromStatement(stmt, "orders-staging", qualifiers)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				if inputs != nil {
+					t.Errorf("a skipped statement returned %d inputs", len(inputs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var actions []string
+			for _, input := range inputs {
+				if aws.ToString(input.FunctionName) != "orders-staging" {
+					t.Errorf("function name = %q, want the clone", aws.ToString(input.FunctionName))
+				}
+				actions = append(actions, aws.ToString(input.Action))
+			}
+			if len(inputs) > 1 && aws.ToString(inputs[0].StatementId) == aws.ToString(inputs[1].StatementId) {
+				t.Errorf("statement ids repeat: %s", aws.ToString(inputs[0].StatementId))
+			}
+			first := inputs[0]
+			tt.check(t, aws.ToString(first.StatementId), aws.ToString(first.Principal), aws.ToString(first.Qualifier),
+				aws.ToString(first.SourceArn), aws.ToString(first.SourceAccount), actions)
+		})
+	}
+}
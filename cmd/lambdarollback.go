@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+type cloneUndo struct {
+	label string
+	undo  func(ctx context.Context) error
+}
+
+func (j *cloneJob) created(label string, undo func(ctx context.Context) error) {
+	j.undo = append(j.undo, cloneUndo{label: label, undo: undo})
+}
+
+// rollback only deletes mappings on their own, deleting the function takes its versions, urls,
+// async invoke configs and policy with it
+func (j *cloneJob) rollback() {
+	if len(j.undo) == 0 {
+		return
+	}
+	//the job context may be what failed, cleanup gets its own
+	ctx := context.Background()
+	for i := len(j.undo) - 1; i >= 0; i-- {
+		step := j.undo[i]
+		err := step.undo(ctx)
+		var notFound *types.ResourceNotFoundException
+		if err != nil && !errors.As(err, &notFound) {
+			j.report.warn("rollback could not remove %s: %v", step.label, err)
+			continue
+		}
+		j.report.note("rolled back %s", step.label)
+	}
+	j.undo = nil
+}
+
+func (j *cloneJob) createdFunction() {
+	name := j.newName
+	j.created("function "+name, func(ctx context.Context) error {
+		_, err := j.dst.DeleteFunction(ctx, &lambda.DeleteFunctionInput{FunctionName: aws.String(name)})
+		return err
+	})
+}
+
+func (j *cloneJob) createdAlias(alias string) {
+	name := j.newName
+	j.created("alias "+alias, func(ctx context.Context) error {
+		_, err := j.dst.DeleteAlias(ctx, &lambda.DeleteAliasInput{FunctionName: aws.String(name), Name: aws.String(alias)})
+		return err
+	})
+}
+
+func (j *cloneJob) createdMapping(uuid string, sourceName string) {
+	j.created("event source mapping "+sourceName, func(ctx context.Context) error {
+		_, err := j.dst.DeleteEventSourceMapping(ctx, &lambda.DeleteEventSourceMappingInput{UUID: aws.String(uuid)})
+		return err
+	})
+}
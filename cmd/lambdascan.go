@@ -0,0 +1,407 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// scanVerdict is ordered worst last
+type scanVerdict int
+
+const (
+	verdictNotScanned scanVerdict = iota
+	verdictCompatible
+	verdictReview
+	verdictIncompatible
+)
+
+var verdictLabels = map[scanVerdict]string{
+	verdictNotScanned:   "NOT SCANNED",
+	verdictCompatible:   "COMPATIBLE",
+	verdictReview:       "REVIEW",
+	verdictIncompatible: "INCOMPATIBLE",
+}
+
+type codeScan struct {
+	verdict  scanVerdict
+	findings []string
+}
+
+func (s *codeScan) find(verdict scanVerdict, format string, a ...any) {
+	if verdict > s.verdict {
+		s.verdict = verdict
+	}
+	s.findings = append(s.findings, fmt.Sprintf(format, a...))
+}
+
+var removedPythonModules = map[string]int{
+	"formatter": 10, "parser": 10, "symbol": 10,
+	"binhex":    11,
+	"distutils": 12, "imp": 12, "asynchat": 12, "asyncore": 12, "smtpd": 12,
+	"aifc": 13, "audioop": 13, "cgi": 13, "cgitb": 13, "chunk": 13, "crypt": 13, "imghdr": 13,
+	"mailcap": 13, "msilib": 13, "nis": 13, "nntplib": 13, "ossaudiodev": 13, "pipes": 13,
+	"sndhdr": 13, "spwd": 13, "sunau": 13, "telnetlib": 13, "uu": 13, "xdrlib": 13, "lib2to3": 13,
+}
+
+// pythonWheelFloors is the first release with wheels for a python version, an older pin has nothing
+// to install on the new runtime
+var pythonWheelFloors = map[string]map[int]string{
+	"numpy":           {9: "1.19.3", 10: "1.21.3", 11: "1.23.2", 12: "1.26.0", 13: "2.1.0"},
+	"pandas":          {11: "1.5.0", 12: "2.1.1", 13: "2.2.3"},
+	"scipy":           {11: "1.9.2", 12: "1.11.2", 13: "1.14.1"},
+	"pillow":          {11: "9.3.0", 12: "10.1.0", 13: "11.0.0"},
+	"psycopg2-binary": {12: "2.9.9", 13: "2.9.10"},
+	"lxml":            {12: "4.9.3", 13: "5.3.0"},
+}
+
+var (
+	cpythonTag   = regexp.MustCompile(`\.cpython-3(\d+)`)
+	pythonImport = regexp.MustCompile(`^\s*(?:import\s+([\w.]+(?:\s*,\s*[\w.]+)*)|from\s+([\w.]+)\s+import\b)`)
+	requirement  = regexp.MustCompile(`^([A-Za-z0-9_.\-\[\]]+)\s*==\s*([\w.]+)`)
+)
+
+func (app *applicationMain) scanUpgrade(functionName string) (types.Runtime, types.Runtime, *codeScan, error) {
+	scan := &codeScan{}
+	clientLamb, err := app.createLambdaClient()
+	if err != nil {
+		return "", "", scan, fmt.Errorf("failed to create Lambda connection:\n%v", err)
+	}
+	result, err := clientLamb.GetFunction(context.TODO(), &lambda.GetFunctionInput{
+		FunctionName: aws.String(functionName),
+	})
+	if err != nil {
+		return "", "", scan, fmt.Errorf("failed to get function details:\n%v", err)
+	}
+	cfg := result.Configuration
+	if cfg.PackageType == types.PackageTypeImage {
+		scan.find(verdictNotScanned, "container image, no managed runtime to upgrade")
+		return "", "", scan, nil
+	}
+	target, err := app.upgradeRuntime(functionName, cfg.Runtime)
+	if err != nil {
+		return cfg.Runtime, cfg.Runtime, scan, err
+	}
+	if target == cfg.Runtime {
+		scan.find(verdictNotScanned, "already current")
+		return cfg.Runtime, target, scan, nil
+	}
+
+	//layers have to declare the new runtime whatever the language
+	for _, verdict := range checkLayerCompatibility(context.TODO(), clientLamb, cfg.Layers, target, cfg.Architectures) {
+		switch {
+		case verdict.verdict == layerIncompatible && !app.AllowIncompatibleLayers:
+			scan.find(verdictIncompatible, "%s, blocks the upgrade", verdict)
+		case verdict.verdict == layerIncompatible:
+			scan.find(verdictIncompatible, "%s", verdict)
+		case verdict.verdict == layerUnknown:
+			scan.find(verdictReview, "%s", verdict)
+		default:
+			scan.find(verdictCompatible, "%s", verdict)
+		}
+	}
+
+	family, _ := runtimeFamilyOf(cfg.Runtime)
+	if family.name != "python" && family.name != "nodejs" {
+		scan.find(verdictNotScanned, "%s code packages are not scanned", family.name)
+		return cfg.Runtime, target, scan, nil
+	}
+	if result.Code == nil || result.Code.Location == nil {
+		return cfg.Runtime, target, scan, fmt.Errorf("no code location returned for %s", functionName)
+	}
+
+	fileName, _, err := downloadFunctionCode(aws.ToString(result.Code.Location), aws.ToString(cfg.CodeSha256))
+	if err != nil {
+		return cfg.Runtime, target, scan, err
+	}
+	defer os.Remove(fileName)
+	archive, err := zip.OpenReader(fileName)
+	if err != nil {
+		return cfg.Runtime, target, scan, fmt.Errorf("failed to open code package:\n%v", err)
+	}
+	defer archive.Close()
+
+	if family.name == "python" {
+		scanPython(scan, archive.File, cfg.Runtime, target)
+	} else {
+		scanNode(scan, archive.File, target)
+	}
+	if scan.verdict == verdictNotScanned {
+		scan.verdict = verdictCompatible
+	}
+	return cfg.Runtime, target, scan, nil
+}
+
+// pythonMinor is -1 for python 2
+func pythonMinor(runtime types.Runtime) int {
+	version := strings.TrimPrefix(string(runtime), "python")
+	if !strings.HasPrefix(version, "3.") {
+		return -1
+	}
+	minor, err := strconv.Atoi(strings.TrimPrefix(version, "3."))
+	if err != nil {
+		return -1
+	}
+	return minor
+}
+
+func scanPython(scan *codeScan, files []*zip.File, current types.Runtime, target types.Runtime) {
+	from, to := pythonMinor(current), pythonMinor(target)
+	if from < 0 {
+		scan.find(verdictIncompatible, "python 2 code does not run on %s", target)
+	}
+
+	abiTags := map[int][]string{}
+	removed := map[string][]string{}
+	for _, f := range files {
+		name := f.Name
+		switch {
+		case strings.HasSuffix(name, ".so"):
+			//abi3 extensions use the stable ABI and load on any later python
+			if match := cpythonTag.FindStringSubmatch(path.Base(name)); match != nil {
+				minor, _ := strconv.Atoi(match[1])
+				if minor != to {
+					abiTags[minor] = append(abiTags[minor], name)
+				}
+			}
+		case strings.HasSuffix(name, ".py"):
+			for _, module := range pythonImports(f) {
+				if version, ok := removedPythonModules[module]; ok && version > from && version <= to {
+					removed[module] = append(removed[module], name)
+				}
+			}
+		case path.Base(name) == "requirements.txt":
+			scanRequirements(scan, f, to)
+		}
+	}
+
+	for _, minor := range sortedKeys(abiTags) {
+		scan.find(verdictIncompatible, "%d compiled extensions built for python 3.%d, e.g. %s", len(abiTags[minor]), minor, abiTags[minor][0])
+	}
+	for _, module := range sortedKeys(removed) {
+		//an import in the function's own top level modules fails for sure, deeper ones may be guarded
+		verdict := verdictReview
+		for _, name := range removed[module] {
+			if !strings.Contains(name, "/") {
+				verdict = verdictIncompatible
+			}
+		}
+		scan.find(verdict, "imports %s, removed in python 3.%d, in %d files, e.g. %s", module, removedPythonModules[module], len(removed[module]), removed[module][0])
+	}
+}
+
+func pythonImports(f *zip.File) []string {
+	rc, err := f.Open()
+	if err != nil {
+		return nil
+	}
+	defer rc.Close()
+	var modules []string
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		match := pythonImport.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		names := match[2]
+		if names == "" {
+			names = match[1]
+		}
+		for _, name := range strings.Split(names, ",") {
+			modules = append(modules, strings.Split(strings.TrimSpace(name), ".")[0])
+		}
+	}
+	return modules
+}
+
+func scanRequirements(scan *codeScan, f *zip.File, to int) {
+	rc, err := f.Open()
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+	pins := 0
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		match := requirement.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		pins++
+		pkg := strings.ToLower(strings.Split(match[1], "[")[0])
+		if floor, ok := pythonWheelFloors[pkg][to]; ok && compareVersions(match[2], floor) < 0 {
+			scan.find(verdictIncompatible, "%s pins %s==%s, python 3.%d needs %s or later", f.Name, pkg, match[2], to, floor)
+		}
+	}
+	if pins > 0 {
+		scan.find(verdictCompatible, "%s has %d pinned packages", f.Name, pins)
+	}
+}
+
+// compareVersions counts missing or non numeric parts as 0
+func compareVersions(a string, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var va, vb int
+		if i < len(pa) {
+			va, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			vb, _ = strconv.Atoi(pb[i])
+		}
+		if va != vb {
+			if va < vb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func nodeMajor(runtime types.Runtime) int {
+	version := strings.TrimSuffix(strings.TrimPrefix(string(runtime), "nodejs"), ".x")
+	major, err := strconv.Atoi(strings.Split(version, ".")[0])
+	if err != nil {
+		return -1
+	}
+	return major
+}
+
+func scanNode(scan *codeScan, files []*zip.File, target types.Runtime) {
+	major := nodeMajor(target)
+	var addons []string
+	for _, f := range files {
+		switch {
+		case strings.HasSuffix(f.Name, ".node"):
+			addons = append(addons, f.Name)
+		case f.Name == "package.json":
+			scanEngines(scan, f, major)
+		}
+	}
+	if len(addons) > 0 {
+		scan.find(verdictReview, "%d native addons, rebuild them for node %d unless they use N-API, e.g. %s", len(addons), major, addons[0])
+	}
+}
+
+func scanEngines(scan *codeScan, f *zip.File, major int) {
+	rc, err := f.Open()
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return
+	}
+	var pkg struct {
+		Engines map[string]string `json:"engines"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		scan.find(verdictReview, "package.json could not be read: %v", err)
+		return
+	}
+	expr, ok := pkg.Engines["node"]
+	if !ok {
+		return
+	}
+	allowed, known := nodeEngineAllows(expr, major)
+	switch {
+	case !known:
+		scan.find(verdictReview, "engines.node %q could not be checked against node %d", expr, major)
+	case !allowed:
+		scan.find(verdictIncompatible, "engines.node %q excludes node %d", expr, major)
+	}
+}
+
+var nodeComparator = regexp.MustCompile(`^(>=|<=|>|<|=|\^|~)?v?(\d+|x|\*)(?:\.(\d+|x|\*))?(?:\.(\d+|x|\*))?`)
+
+// nodeEngineAllows only compares majors, which is all a lambda runtime pins. known is false for ranges
+// it cannot read.
+func nodeEngineAllows(expr string, major int) (allowed bool, known bool) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" || expr == "*" || expr == "x" {
+		return true, true
+	}
+	for _, alternative := range strings.Split(expr, "||") {
+		all := true
+		fields := strings.Fields(strings.ReplaceAll(alternative, " - ", " "))
+		if strings.Contains(alternative, " - ") && len(fields) == 2 {
+			//a hyphen range is inclusive at both ends
+			fields = []string{">=" + fields[0], "<=" + fields[1]}
+		}
+		for _, field := range fields {
+			match := nodeComparator.FindStringSubmatch(field)
+			if match == nil || len(match[0]) != len(field) {
+				return false, false
+			}
+			if match[2] == "x" || match[2] == "*" {
+				continue
+			}
+			//lambda runs the newest release of a major, so 18.5 bounds are read against 18.x at its latest
+			v, _ := strconv.Atoi(match[2])
+			majorOnly := match[3] == "" || match[3] == "x" || match[3] == "*"
+			switch match[1] {
+			case ">=":
+				all = all && major >= v
+			case ">":
+				all = all && (major > v || (major == v && !majorOnly))
+			case "<=":
+				all = all && (major < v || (major == v && majorOnly))
+			case "<":
+				all = all && major < v
+			default:
+				all = all && major == v
+			}
+		}
+		if all {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+func sortedKeys[V any, K int | string](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool { return keys[a] < keys[b] })
+	return keys
+}
+
+func (app *applicationMain) upgradeScanPreview(functionNames []string) string {
+	var sections []string
+	for _, name := range functionNames {
+		current, target, scan, err := app.scanUpgrade(name)
+		if err != nil {
+			scan.find(verdictReview, "scan failed: %v", err)
+		}
+		heading := name
+		if current != target {
+			heading = fmt.Sprintf("%s   %s -> %s", name, current, target)
+		} else if current != "" {
+			heading = fmt.Sprintf("%s   %s", name, current)
+		}
+		lines := []string{fmt.Sprintf("%s   %s", heading, verdictLabels[scan.verdict])}
+		for _, finding := range scan.findings {
+			lines = append(lines, "    "+finding)
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+	return strings.Join(sections, "\n")
+}
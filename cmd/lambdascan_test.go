@@ -0,0 +1,207 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// codePackage builds an in memory code package from file names and contents
+func codePackage(t *testing.T, files map[string]string) []*zip.File {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range sortedKeys(files) {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.File
+}
+
+func TestNodeEngineAllows(t *testing.T) {
+	tests := []struct {
+		expr    string
+		major   int
+		allowed bool
+		known   bool
+	}{
+		{"", 22, true, true},
+		{"*", 22, true, true},
+		{">=18", 22, true, true},
+		{">=18", 16, false, true},
+		{">=18.12.0", 18, true, true},
+		{"^18.0.0", 18, true, true},
+		{"^18.0.0", 20, false, true},
+		{"~20.1", 20, true, true},
+		{"18.x", 18, true, true},
+		{"<20", 20, false, true},
+		{"<20", 18, true, true},
+		{"<=20", 20, true, true},
+		{"<=20.5", 20, false, true},
+		{">18", 18, false, true},
+		{">18.5", 18, true, true},
+		{"16 - 20", 20, true, true},
+		{"16 - 20", 22, false, true},
+		{">=16 <20", 20, false, true},
+		{"^16 || ^18 || ^20", 18, true, true},
+		{"^16 || ^18 || ^20", 22, false, true},
+		{"latest", 22, false, false},
+		{">=18 foo", 22, false, false},
+	}
+	for _, tt := range tests {
+		allowed, known := nodeEngineAllows(tt.expr, tt.major)
+		if allowed != tt.allowed || known != tt.known {
+			t.Errorf("nodeEngineAllows(%q, %d) = %v, %v, want %v, %v", tt.expr, tt.major, allowed, known, tt.allowed, tt.known)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.26.0", "1.26.0", 0},
+		{"1.26", "1.26.0", 0},
+		{"1.9.2", "1.11.2", -1},
+		{"2.1.0", "1.26.4", 1},
+		{"10.0", "9.5.1", 1},
+		{"1.0rc1", "1.0.0", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRuntimeVersions(t *testing.T) {
+	python := map[types.Runtime]int{types.RuntimePython313: 13, types.RuntimePython39: 9, types.RuntimePython27: -1, types.RuntimeNodejs22x: -1}
+	for runtime, want := range python {
+		if got := pythonMinor(runtime); got != want {
+			t.Errorf("pythonMinor(%s) = %d, want %d", runtime, got, want)
+		}
+	}
+	node := map[types.Runtime]int{types.RuntimeNodejs22x: 22, types.RuntimeNodejs43: 4, types.RuntimeNodejs: -1, types.RuntimePython313: -1}
+	for runtime, want := range node {
+		if got := nodeMajor(runtime); got != want {
+			t.Errorf("nodeMajor(%s) = %d, want %d", runtime, got, want)
+		}
+	}
+}
+
+func TestScanPython(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		current  types.Runtime
+		verdict  scanVerdict
+		findings []string
+	}{
+		{
+			name:    "pure python",
+			files:   map[string]string{"handler.py": "import json\nfrom os import path\n"},
+			current: types.RuntimePython39,
+			verdict: verdictNotScanned,
+		},
+		{
+			name:     "extension built for the old python",
+			files:    map[string]string{"numpy/core/_multiarray.cpython-39-x86_64-linux-gnu.so": ""},
+			current:  types.RuntimePython39,
+			verdict:  verdictIncompatible,
+			findings: []string{"built for python 3.9"},
+		},
+		{
+			name:    "extension built for the target python",
+			files:   map[string]string{"numpy/core/_multiarray.cpython-313-x86_64-linux-gnu.so": ""},
+			current: types.RuntimePython39,
+			verdict: verdictNotScanned,
+		},
+		{
+			name:     "two digit minor is not read as one",
+			files:    map[string]string{"pkg/_speedups.cpython-312-x86_64-linux-gnu.so": ""},
+			current:  types.RuntimePython312,
+			verdict:  verdictIncompatible,
+			findings: []string{"built for python 3.12"},
+		},
+		{
+			name:    "abi3 extension loads anywhere",
+			files:   map[string]string{"cryptography/hazmat/bindings/_rust.abi3.so": ""},
+			current: types.RuntimePython39,
+			verdict: verdictNotScanned,
+		},
+		{
+			name:     "requirements pin below the wheel floor",
+			files:    map[string]string{"requirements.txt": "numpy==1.24.0\nrequests==2.31.0\n"},
+			current:  types.RuntimePython39,
+			verdict:  verdictIncompatible,
+			findings: []string{"numpy==1.24.0, python 3.13 needs 2.1.0", "2 pinned packages"},
+		},
+		{
+			name:     "requirements pins that have wheels",
+			files:    map[string]string{"requirements.txt": "numpy == 2.1.3\nPandas[performance]==2.2.3\n# a comment\n"},
+			current:  types.RuntimePython39,
+			verdict:  verdictCompatible,
+			findings: []string{"2 pinned packages"},
+		},
+		{
+			name:     "removed module imported by the function",
+			files:    map[string]string{"handler.py": "import os, imp\n"},
+			current:  types.RuntimePython39,
+			verdict:  verdictIncompatible,
+			findings: []string{"imports imp, removed in python 3.12"},
+		},
+		{
+			name:     "removed module imported by a dependency",
+			files:    map[string]string{"vendor/legacy.py": "from distutils.version import LooseVersion\n"},
+			current:  types.RuntimePython39,
+			verdict:  verdictReview,
+			findings: []string{"imports distutils, removed in python 3.12"},
+		},
+		{
+			name:    "module removed before the current python",
+			files:   map[string]string{"handler.py": "import parser\n"},
+			current: types.RuntimePython311,
+			verdict: verdictNotScanned,
+		},
+		{
+			name:     "python 2",
+			files:    map[string]string{"handler.py": "import json\n"},
+			current:  types.RuntimePython27,
+			verdict:  verdictIncompatible,
+			findings: []string{"python 2 code"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scan := &codeScan{}
+			scanPython(scan, codePackage(t, tt.files), tt.current, types.RuntimePython313)
+			if scan.verdict != tt.verdict {
+				t.Errorf("verdict = %s, want %s, findings %q", verdictLabels[scan.verdict], verdictLabels[tt.verdict], scan.findings)
+			}
+			if len(scan.findings) != len(tt.findings) {
+				t.Fatalf("findings = %q, want %d", scan.findings, len(tt.findings))
+			}
+			for i, want := range tt.findings {
+				if !strings.Contains(scan.findings[i], want) {
+					t.Errorf("finding %q does not mention %q", scan.findings[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+const (
+	defaultPayloadDirectory = "payloads"
+	//the response body is cut on the result screen, the log tail is already limited to 4 KB by lambda
+	smokeResponseLimit = 1000
+)
+
+// payloadDirectory holds <function>.json events and the <function>.expected.json a response has to contain
+func (app *applicationMain) payloadDirectory() string {
+	if app.PayloadDirectory != "" {
+		return app.PayloadDirectory
+	}
+	return defaultPayloadDirectory
+}
+
+func (app *applicationMain) savedPayload(names ...string) (payload []byte, expected []byte, fileName string, err error) {
+	for _, name := range names {
+		fileName = filepath.Join(app.payloadDirectory(), name+".json")
+		payload, err = os.ReadFile(fileName)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fileName, fmt.Errorf("failed to read test event %s:\n%v", fileName, err)
+		}
+		if !json.Valid(payload) {
+			return nil, nil, fileName, fmt.Errorf("test event %s is not valid JSON", fileName)
+		}
+		expectedName := filepath.Join(app.payloadDirectory(), name+".expected.json")
+		expected, err = os.ReadFile(expectedName)
+		if os.IsNotExist(err) {
+			return payload, nil, fileName, nil
+		}
+		if err != nil {
+			return nil, nil, fileName, fmt.Errorf("failed to read expected response %s:\n%v", expectedName, err)
+		}
+		return payload, expected, fileName, nil
+	}
+	return nil, nil, "", nil
+}
+
+// smokeTest looks for the event under each of names in turn, a clone falls back to its source's
+func (app *applicationMain) smokeTest(ctx context.Context, clientLamb *lambda.Client, functionName string, qualifier string, names []string, report *lambdaReport) error {
+	if !app.SmokeTest {
+		return nil
+	}
+	payload, expected, fileName, err := app.savedPayload(names...)
+	if err != nil {
+		return err
+	}
+	if payload == nil {
+		report.note("no test event in %s/%s.json, smoke test skipped", app.payloadDirectory(), names[0])
+		return nil
+	}
+	//a function just updated may still be settling
+	if err = app.waitForFunctionUpdate(ctx, clientLamb, functionName); err != nil {
+		return err
+	}
+
+	input := &lambda.InvokeInput{
+		FunctionName: aws.String(functionName),
+		Payload:      payload,
+		LogType:      types.LogTypeTail,
+	}
+	if qualifier != "" {
+		input.Qualifier = aws.String(qualifier)
+	}
+	resp, err := clientLamb.Invoke(ctx, input)
+	if err != nil {
+		return fmt.Errorf("smoke test invocation failed:\n%v", err)
+	}
+
+	report.note("smoke test with %s: status %d", fileName, resp.StatusCode)
+	body := string(resp.Payload)
+	if len(body) > smokeResponseLimit {
+		body = body[:smokeResponseLimit] + "..."
+	}
+	report.note("  response %s", body)
+	if logs, err := base64.StdEncoding.DecodeString(aws.ToString(resp.LogResult)); err == nil && len(logs) > 0 {
+		report.note("  log tail:")
+		for _, line := range strings.Split(strings.TrimRight(string(logs), "\n"), "\n") {
+			report.note("    %s", line)
+		}
+	}
+
+	if resp.FunctionError != nil {
+		return fmt.Errorf("smoke test failed, function error %s", aws.ToString(resp.FunctionError))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("smoke test failed with status %d", resp.StatusCode)
+	}
+	if expected == nil {
+		return nil
+	}
+	var want, got any
+	if err := json.Unmarshal(expected, &want); err != nil {
+		return fmt.Errorf("expected response for %s is not valid JSON: %v", functionName, err)
+	}
+	if err := json.Unmarshal(resp.Payload, &got); err != nil {
+		return fmt.Errorf("smoke test failed, the response is not JSON: %v", err)
+	}
+	if mismatch := jsonSubset(want, got, "$"); mismatch != "" {
+		return fmt.Errorf("smoke test failed, the response does not match the expected JSON: %s", mismatch)
+	}
+	report.note("  response matches the expected JSON")
+	return nil
+}
+
+// jsonSubset allows extra object keys in got, it returns the path of the first difference or ""
+func jsonSubset(want any, got any, path string) string {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return fmt.Sprintf("%s is not an object", path)
+		}
+		for key, value := range w {
+			inner, ok := g[key]
+			if !ok {
+				return fmt.Sprintf("%s.%s is missing", path, key)
+			}
+			if mismatch := jsonSubset(value, inner, path+"."+key); mismatch != "" {
+				return mismatch
+			}
+		}
+		return ""
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			return fmt.Sprintf("%s is not an array", path)
+		}
+		if len(g) < len(w) {
+			return fmt.Sprintf("%s has %d elements, expected at least %d", path, len(g), len(w))
+		}
+		for i := range w {
+			if mismatch := jsonSubset(w[i], g[i], fmt.Sprintf("%s[%d]", path, i)); mismatch != "" {
+				return mismatch
+			}
+		}
+		return ""
+	default:
+		//strings, float64 numbers, bools and nil compare directly
+		if want != got {
+			return fmt.Sprintf("%s is %v, expected %v", path, got, want)
+		}
+		return ""
+	}
+}
@@ -0,0 +1,609 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// sync updates an existing clone in place and reconciles everything cloneLambda would have created
+func (j *cloneJob) sync(existing *lambda.GetFunctionOutput) error {
+	ctx := j.ctx
+	j.syncing = true
+	j.previous = existing.Configuration
+	j.report.title += " (sync)"
+	source := j.source.Configuration
+	target := existing.Configuration
+
+	if source.PackageType != target.PackageType {
+		return fmt.Errorf("%s is a %s function and cannot be synced from a %s function", j.newName, target.PackageType, source.PackageType)
+	}
+
+	//an update still running on the clone would reject ours
+	if err := j.app.waitForFunctionUpdate(ctx, j.dst, j.newName); err != nil {
+		return err
+	}
+
+	//code and configuration
+	in := j.newFunctionInput(source)
+	changes := configChanges(target, in)
+	if aws.ToString(source.CodeSha256) != aws.ToString(target.CodeSha256) {
+		changes = append([]string{"code " + aws.ToString(source.CodeSha256)}, changes...)
+	}
+	if _, err := j.syncCloneLatest(j.source, aws.ToString(target.CodeSha256)); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		j.report.note("~ %s", change)
+	}
+
+	if err := j.cloneRuntimeSettings(in.Runtime); err != nil {
+		return err
+	}
+	if err := j.syncTags(source.FunctionArn, target.FunctionArn); err != nil {
+		return err
+	}
+	if err := j.syncConcurrency(); err != nil {
+		return err
+	}
+	if err := j.syncAliases(); err != nil {
+		return err
+	}
+	if err := j.syncEventSourceMappings(); err != nil {
+		return err
+	}
+
+	//urls are updated in place, async invoke configs and provisioned concurrency are overwritten
+	if err := j.cloneFunctionUrls(); err != nil {
+		return err
+	}
+	if err := j.cloneEventInvokeConfigs(); err != nil {
+		return err
+	}
+	if err := j.cloneProvisionedConcurrency(); err != nil {
+		return err
+	}
+	return j.syncPolicy()
+}
+
+func configChanges(current *types.FunctionConfiguration, in *lambda.CreateFunctionInput) []string {
+	var changes []string
+	changed := func(name string, from, to any) {
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, fmt.Sprintf("%s %v -> %v", name, from, to))
+		}
+	}
+	changed("runtime", current.Runtime, in.Runtime)
+	changed("handler", aws.ToString(current.Handler), aws.ToString(in.Handler))
+	changed("memory", aws.ToInt32(current.MemorySize), aws.ToInt32(in.MemorySize))
+	changed("timeout", aws.ToInt32(current.Timeout), aws.ToInt32(in.Timeout))
+	changed("role", aws.ToString(current.Role), aws.ToString(in.Role))
+	changed("description", aws.ToString(current.Description), aws.ToString(in.Description))
+
+	var layers []string
+	for _, layer := range current.Layers {
+		layers = append(layers, aws.ToString(layer.Arn))
+	}
+	if strings.Join(layers, ",") != strings.Join(in.Layers, ",") {
+		changes = append(changes, fmt.Sprintf("layers [%s] -> [%s]", strings.Join(layers, ", "), strings.Join(in.Layers, ", ")))
+	}
+
+	var currentEnv, newEnv map[string]string
+	if current.Environment != nil {
+		currentEnv = current.Environment.Variables
+	}
+	if in.Environment != nil {
+		newEnv = in.Environment.Variables
+	}
+	var envKeys []string
+	for k, v := range newEnv {
+		if old, ok := currentEnv[k]; !ok || old != v {
+			envKeys = append(envKeys, k)
+		}
+	}
+	for k := range currentEnv {
+		if _, ok := newEnv[k]; !ok {
+			envKeys = append(envKeys, k)
+		}
+	}
+	if len(envKeys) > 0 {
+		sort.Strings(envKeys)
+		changes = append(changes, "environment variables "+strings.Join(envKeys, ", "))
+	}
+
+	var subnets []string
+	if current.VpcConfig != nil {
+		subnets = current.VpcConfig.SubnetIds
+	}
+	var newSubnets []string
+	if in.VpcConfig != nil {
+		newSubnets = in.VpcConfig.SubnetIds
+	}
+	if strings.Join(subnets, ",") != strings.Join(newSubnets, ",") {
+		changes = append(changes, fmt.Sprintf("vpc subnets [%s] -> [%s]", strings.Join(subnets, ", "), strings.Join(newSubnets, ", ")))
+	}
+	return changes
+}
+
+func (j *cloneJob) syncTags(sourceArn *string, targetArn *string) error {
+	ctx := j.ctx
+	sourceResp, err := j.src.ListTags(ctx, &lambda.ListTagsInput{Resource: sourceArn})
+	if err != nil {
+		return fmt.Errorf("failed to list tags: %v", err)
+	}
+	targetResp, err := j.dst.ListTags(ctx, &lambda.ListTagsInput{Resource: targetArn})
+	if err != nil {
+		return fmt.Errorf("failed to list tags of %s: %v", j.newName, err)
+	}
+
+	want := j.cloneTags(sourceResp.Tags)
+	//the clone keeps the time it was first cloned at
+	if clonedAt, ok := targetResp.Tags[tagClonedAt]; ok && j.app.ProvenanceTags {
+		want[tagClonedAt] = clonedAt
+	}
+
+	set := map[string]string{}
+	for k, v := range want {
+		if old, ok := targetResp.Tags[k]; !ok || old != v {
+			set[k] = v
+		}
+	}
+	var remove []string
+	for k := range targetResp.Tags {
+		if _, ok := want[k]; !ok {
+			remove = append(remove, k)
+		}
+	}
+	sort.Strings(remove)
+
+	if len(set) > 0 {
+		_, err = j.dst.TagResource(ctx, &lambda.TagResourceInput{Resource: targetArn, Tags: set})
+		if err != nil {
+			return fmt.Errorf("failed to tag %s: %v", j.newName, err)
+		}
+		j.report.note("~ tags set: %s", formatTags(set))
+	}
+	if len(remove) > 0 {
+		_, err = j.dst.UntagResource(ctx, &lambda.UntagResourceInput{Resource: targetArn, TagKeys: remove})
+		if err != nil {
+			return fmt.Errorf("failed to untag %s: %v", j.newName, err)
+		}
+		j.report.note("- tags removed: %s", strings.Join(remove, ", "))
+	}
+	return nil
+}
+
+func (j *cloneJob) syncConcurrency() error {
+	ctx := j.ctx
+	sourceResp, err := j.src.GetFunctionConcurrency(ctx, &lambda.GetFunctionConcurrencyInput{FunctionName: aws.String(j.functionName)})
+	if err != nil {
+		return fmt.Errorf("failed to get concurrency: %v", err)
+	}
+	targetResp, err := j.dst.GetFunctionConcurrency(ctx, &lambda.GetFunctionConcurrencyInput{FunctionName: aws.String(j.newName)})
+	if err != nil {
+		return fmt.Errorf("failed to get concurrency of %s: %v", j.newName, err)
+	}
+	want, have := sourceResp.ReservedConcurrentExecutions, targetResp.ReservedConcurrentExecutions
+	switch {
+	case want == nil && have != nil:
+		_, err = j.dst.DeleteFunctionConcurrency(ctx, &lambda.DeleteFunctionConcurrencyInput{FunctionName: aws.String(j.newName)})
+		if err != nil {
+			return fmt.Errorf("failed to remove concurrency of %s: %v", j.newName, err)
+		}
+		j.report.note("- reserved concurrency %d removed", aws.ToInt32(have))
+	case want != nil && aws.ToInt32(want) != aws.ToInt32(have):
+		_, err = j.dst.PutFunctionConcurrency(ctx, &lambda.PutFunctionConcurrencyInput{
+			FunctionName:                 aws.String(j.newName),
+			ReservedConcurrentExecutions: want,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set concurrency on %s: %v", j.newName, err)
+		}
+		j.report.note("~ reserved concurrency %d", aws.ToInt32(want))
+	}
+	return nil
+}
+
+func (j *cloneJob) listVersionConfigs(clientLamb *lambda.Client, functionName string) ([]types.FunctionConfiguration, error) {
+	var versions []types.FunctionConfiguration
+	paginator := lambda.NewListVersionsByFunctionPaginator(clientLamb, &lambda.ListVersionsByFunctionInput{
+		FunctionName: aws.String(functionName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(j.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions of %s: %v", functionName, err)
+		}
+		for _, v := range page.Versions {
+			if aws.ToString(v.Version) != latestVersion {
+				versions = append(versions, v)
+			}
+		}
+	}
+	return versions, nil
+}
+
+// sameVersion compares the runtime the clone would get, not the source's
+func (j *cloneJob) sameVersion(source types.FunctionConfiguration, clone types.FunctionConfiguration) bool {
+	runtime := source.Runtime
+	if j.upgrade2 && source.PackageType != types.PackageTypeImage {
+		runtime, _ = j.app.upgradeRuntime(j.functionName, source.Runtime)
+	}
+	return aws.ToString(source.CodeSha256) == aws.ToString(clone.CodeSha256) &&
+		aws.ToString(source.Handler) == aws.ToString(clone.Handler) &&
+		aws.ToString(source.Description) == aws.ToString(clone.Description) &&
+		runtime == clone.Runtime
+}
+
+// syncAliases reuses clone versions already published from the source versions the aliases need
+func (j *cloneJob) syncAliases() error {
+	ctx := j.ctx
+	j.qualifiers[latestVersion] = latestVersion
+
+	aliases, versions, err := j.aliasesToClone()
+	if err != nil {
+		return err
+	}
+	sourceVersions, err := j.listVersionConfigs(j.src, j.functionName)
+	if err != nil {
+		return err
+	}
+	cloneVersions, err := j.listVersionConfigs(j.dst, j.newName)
+	if err != nil {
+		return err
+	}
+	sourceByVersion := map[string]types.FunctionConfiguration{}
+	for _, v := range sourceVersions {
+		sourceByVersion[aws.ToString(v.Version)] = v
+	}
+
+	cloneSha := aws.ToString(j.source.Configuration.CodeSha256)
+	published := false
+	for _, version := range versions {
+		//newest matching clone version wins
+		for i := len(cloneVersions) - 1; i >= 0; i-- {
+			if j.sameVersion(sourceByVersion[version], cloneVersions[i]) {
+				j.qualifiers[version] = aws.ToString(cloneVersions[i].Version)
+				break
+			}
+		}
+		if _, ok := j.qualifiers[version]; ok {
+			continue
+		}
+		newVersion, sha, err := j.publishCloneVersion(version, cloneSha)
+		cloneSha = sha
+		if err != nil {
+			return err
+		}
+		published = true
+		j.qualifiers[version] = newVersion
+		j.report.note("+ version %s -> clone version %s", version, newVersion)
+	}
+	if published {
+		if _, err := j.syncCloneLatest(j.source, cloneSha); err != nil {
+			return err
+		}
+	}
+
+	existing := map[string]types.AliasConfiguration{}
+	paginator := lambda.NewListAliasesPaginator(j.dst, &lambda.ListAliasesInput{FunctionName: aws.String(j.newName)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list aliases of %s: %v", j.newName, err)
+		}
+		for _, alias := range page.Aliases {
+			existing[aws.ToString(alias.Name)] = alias
+		}
+	}
+
+	for _, alias := range aliases {
+		name := aws.ToString(alias.Name)
+		input, err := j.aliasInput(alias)
+		if err != nil {
+			return err
+		}
+		j.qualifiers[name] = name
+		current, ok := existing[name]
+		delete(existing, name)
+		if !ok {
+			_, err = j.dst.CreateAlias(ctx, input)
+			if err != nil {
+				return fmt.Errorf("failed to create alias on %s: %v", j.newName, err)
+			}
+			j.createdAlias(name)
+			j.report.note("+ alias %s -> clone version %s", name, aws.ToString(input.FunctionVersion))
+			continue
+		}
+
+		var currentWeights, newWeights map[string]float64
+		if current.RoutingConfig != nil {
+			currentWeights = current.RoutingConfig.AdditionalVersionWeights
+		}
+		if input.RoutingConfig != nil {
+			newWeights = input.RoutingConfig.AdditionalVersionWeights
+		}
+		if aws.ToString(current.FunctionVersion) == aws.ToString(input.FunctionVersion) &&
+			aws.ToString(current.Description) == aws.ToString(input.Description) &&
+			len(currentWeights) == len(newWeights) && (len(newWeights) == 0 || reflect.DeepEqual(currentWeights, newWeights)) {
+			continue
+		}
+		routing := input.RoutingConfig
+		if routing == nil {
+			//an empty routing config clears weights left on the clone
+			routing = &types.AliasRoutingConfiguration{AdditionalVersionWeights: map[string]float64{}}
+		}
+		_, err = j.dst.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+			FunctionName:    aws.String(j.newName),
+			Name:            input.Name,
+			FunctionVersion: input.FunctionVersion,
+			Description:     input.Description,
+			RoutingConfig:   routing,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update alias %s on %s: %v", name, j.newName, err)
+		}
+		j.report.note("~ alias %s -> clone version %s", name, aws.ToString(input.FunctionVersion))
+	}
+
+	//aliases the source no longer has
+	var extra []string
+	for name := range existing {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		_, err := j.dst.DeleteAlias(ctx, &lambda.DeleteAliasInput{FunctionName: aws.String(j.newName), Name: aws.String(name)})
+		if err != nil {
+			return fmt.Errorf("failed to delete alias %s on %s: %v", name, j.newName, err)
+		}
+		j.report.note("- alias %s", name)
+	}
+	return nil
+}
+
+func mappingKey(eventSourceArn *string, selfManaged *types.SelfManagedEventSource, topics []string, qualifier string) string {
+	source := mappingSourceName(types.EventSourceMappingConfiguration{EventSourceArn: eventSourceArn, SelfManagedEventSource: selfManaged})
+	return source + "|" + strings.Join(topics, ",") + "|" + qualifier
+}
+
+func mappingChanges(current types.EventSourceMappingConfiguration, in *lambda.CreateEventSourceMappingInput) []string {
+	var changes []string
+	state := aws.ToString(current.State)
+	if (state == "Enabled" || state == "Enabling") != aws.ToBool(in.Enabled) {
+		changes = append(changes, mappingState(in))
+	}
+	if aws.ToInt32(current.BatchSize) != aws.ToInt32(in.BatchSize) && in.BatchSize != nil {
+		changes = append(changes, "batch size")
+	}
+	if aws.ToInt32(current.MaximumBatchingWindowInSeconds) != aws.ToInt32(in.MaximumBatchingWindowInSeconds) {
+		changes = append(changes, "batching window")
+	}
+	if !reflect.DeepEqual(current.FilterCriteria, in.FilterCriteria) {
+		changes = append(changes, "filter criteria")
+	}
+	if aws.ToInt32(current.MaximumRetryAttempts) != aws.ToInt32(in.MaximumRetryAttempts) ||
+		aws.ToInt32(current.MaximumRecordAgeInSeconds) != aws.ToInt32(in.MaximumRecordAgeInSeconds) ||
+		aws.ToBool(current.BisectBatchOnFunctionError) != aws.ToBool(in.BisectBatchOnFunctionError) {
+		changes = append(changes, "error handling")
+	}
+	if aws.ToInt32(current.ParallelizationFactor) != aws.ToInt32(in.ParallelizationFactor) {
+		changes = append(changes, "parallelization factor")
+	}
+	if !reflect.DeepEqual(current.ScalingConfig, in.ScalingConfig) {
+		changes = append(changes, "scaling")
+	}
+	if !reflect.DeepEqual(current.DestinationConfig, in.DestinationConfig) {
+		changes = append(changes, "on-failure destination")
+	}
+	return changes
+}
+
+func (j *cloneJob) syncEventSourceMappings() error {
+	ctx := j.ctx
+	planned, err := j.mappingsToClone()
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]types.EventSourceMappingConfiguration{}
+	paginator := lambda.NewListEventSourceMappingsPaginator(j.dst, &lambda.ListEventSourceMappingsInput{
+		FunctionName: aws.String(j.newName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list event source mappings of %s: %v", j.newName, err)
+		}
+		for _, m := range page.EventSourceMappings {
+			existing[mappingKey(m.EventSourceArn, m.SelfManagedEventSource, m.Topics, arnQualifier(aws.ToString(m.FunctionArn)))] = m
+		}
+	}
+
+	var missing []plannedMapping
+	for _, m := range planned {
+		_, qualifier, _ := strings.Cut(aws.ToString(m.input.FunctionName), ":")
+		key := mappingKey(m.input.EventSourceArn, m.input.SelfManagedEventSource, m.input.Topics, qualifier)
+		current, ok := existing[key]
+		delete(existing, key)
+		if !ok {
+			missing = append(missing, m)
+			continue
+		}
+		changes := mappingChanges(current, m.input)
+		if len(changes) == 0 {
+			continue
+		}
+		in := m.input
+		_, err := j.dst.UpdateEventSourceMapping(ctx, &lambda.UpdateEventSourceMappingInput{
+			UUID:                           current.UUID,
+			FunctionName:                   in.FunctionName,
+			Enabled:                        in.Enabled,
+			BatchSize:                      in.BatchSize,
+			BisectBatchOnFunctionError:     in.BisectBatchOnFunctionError,
+			DestinationConfig:              in.DestinationConfig,
+			DocumentDBEventSourceConfig:    in.DocumentDBEventSourceConfig,
+			FilterCriteria:                 in.FilterCriteria,
+			FunctionResponseTypes:          in.FunctionResponseTypes,
+			KMSKeyArn:                      in.KMSKeyArn,
+			MaximumBatchingWindowInSeconds: in.MaximumBatchingWindowInSeconds,
+			MaximumRecordAgeInSeconds:      in.MaximumRecordAgeInSeconds,
+			MaximumRetryAttempts:           in.MaximumRetryAttempts,
+			MetricsConfig:                  in.MetricsConfig,
+			ParallelizationFactor:          in.ParallelizationFactor,
+			ProvisionedPollerConfig:        in.ProvisionedPollerConfig,
+			ScalingConfig:                  in.ScalingConfig,
+			SourceAccessConfigurations:     in.SourceAccessConfigurations,
+			TumblingWindowInSeconds:        in.TumblingWindowInSeconds,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update event source mapping %s on %s: %v", m.sourceName, j.newName, err)
+		}
+		j.report.note("~ event source mapping %s (%s)", m.sourceName, strings.Join(changes, ", "))
+	}
+
+	for _, m := range missing {
+		newMapping, err := j.dst.CreateEventSourceMapping(ctx, m.input)
+		if err != nil {
+			return fmt.Errorf("failed to create event source mapping on %s: %v", j.newName, err)
+		}
+		j.createdMapping(aws.ToString(newMapping.UUID), m.sourceName)
+		j.report.note("+ event source mapping %s (%s)", m.sourceName, mappingState(m.input))
+	}
+
+	for _, m := range existing {
+		_, err := j.dst.DeleteEventSourceMapping(ctx, &lambda.DeleteEventSourceMappingInput{UUID: m.UUID})
+		if err != nil {
+			return fmt.Errorf("failed to delete event source mapping %s on %s: %v", mappingSourceName(m), j.newName, err)
+		}
+		j.report.note("- event source mapping %s", mappingSourceName(m))
+	}
+	return nil
+}
+
+// permissionSignature is what makes two permissions the same grant
+func permissionSignature(in *lambda.AddPermissionInput) string {
+	return strings.Join([]string{
+		aws.ToString(in.Action), aws.ToString(in.Principal), aws.ToString(in.SourceArn), aws.ToString(in.SourceAccount),
+		aws.ToString(in.PrincipalOrgID), string(in.FunctionUrlAuthType), aws.ToString(in.EventSourceToken),
+	}, "|")
+}
+
+// permissionChanges works on keys of qualifier and statement id, each result is sorted
+func permissionChanges(desired map[string]*lambda.AddPermissionInput, existing map[string]*lambda.AddPermissionInput) (add []string, replace []string, remove []string) {
+	for key, input := range desired {
+		current, ok := existing[key]
+		switch {
+		case !ok:
+			add = append(add, key)
+		case permissionSignature(current) != permissionSignature(input):
+			replace = append(replace, key)
+		}
+	}
+	for key := range existing {
+		if _, ok := desired[key]; !ok {
+			remove = append(remove, key)
+		}
+	}
+	sort.Strings(add)
+	sort.Strings(replace)
+	sort.Strings(remove)
+	return add, replace, remove
+}
+
+func (j *cloneJob) syncPolicy() error {
+	ctx := j.ctx
+	permissionKey := func(qualifier string, sid string) string { return qualifier + "|" + sid }
+
+	desired := map[string]*lambda.AddPermissionInput{}
+	permissions, err := j.policyPermissions()
+	if err != nil {
+		return err
+	}
+	for _, input := range permissions {
+		key := permissionKey(aws.ToString(input.Qualifier), aws.ToString(input.StatementId))
+		desired[key] = input
+	}
+
+	//the clone's own policies, on the function and every qualifier it has
+	targetQualifiers := map[string]string{"": ""}
+	for _, qualifier := range j.qualifiers {
+		if qualifier != latestVersion {
+			targetQualifiers[qualifier] = qualifier
+		}
+	}
+	existing := map[string]*lambda.AddPermissionInput{}
+	for qualifier := range targetQualifiers {
+		input := &lambda.GetPolicyInput{FunctionName: aws.String(j.newName)}
+		if qualifier != "" {
+			input.Qualifier = aws.String(qualifier)
+		}
+		policyResp, err := j.dst.GetPolicy(ctx, input)
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read resource policy of %s:\n%v", j.newName, err)
+		}
+		if policyResp.Policy == nil {
+			continue
+		}
+		var policy lambdaPolicy
+		if err := json.Unmarshal([]byte(*policyResp.Policy), &policy); err != nil {
+			return fmt.Errorf("failed to read resource policy of %s: %v", j.newName, err)
+		}
+		for _, stmt := range policy.Statement {
+			key := permissionKey(qualifier, stmt.Sid)
+			inputs, err := permissionsFromStatement(stmt, j.newName, targetQualifiers)
+			if err != nil || len(inputs) != 1 {
+				//not something a clone would have written, it does not match the source
+				existing[key] = &lambda.AddPermissionInput{StatementId: aws.String(stmt.Sid)}
+				continue
+			}
+			existing[key] = inputs[0]
+		}
+	}
+
+	remove := func(key string) error {
+		qualifier, sid, _ := strings.Cut(key, "|")
+		input := &lambda.RemovePermissionInput{FunctionName: aws.String(j.newName), StatementId: aws.String(sid)}
+		if qualifier != "" {
+			input.Qualifier = aws.String(qualifier)
+		}
+		if _, err := j.dst.RemovePermission(ctx, input); err != nil {
+			return fmt.Errorf("failed to remove permission %s on %s: %v", sid, j.newName, err)
+		}
+		return nil
+	}
+
+	add, replace, extra := permissionChanges(desired, existing)
+	for _, key := range append(replace, add...) {
+		input := desired[key]
+		change := "+"
+		if _, ok := existing[key]; ok {
+			change = "~"
+			if err := remove(key); err != nil {
+				return err
+			}
+		}
+		if _, err := j.dst.AddPermission(ctx, input); err != nil {
+			return fmt.Errorf("failed to add permission %s on %s: %v", aws.ToString(input.StatementId), j.newName, err)
+		}
+		j.report.note("%s permission %s (%s for %s)", change, aws.ToString(input.StatementId), aws.ToString(input.Action), aws.ToString(input.Principal))
+	}
+	for _, key := range extra {
+		if err := remove(key); err != nil {
+			return err
+		}
+		j.report.note("- permission %s", aws.ToString(existing[key].StatementId))
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func permission(sid string, principal string, sourceArn string) *lambda.AddPermissionInput {
+	input := &lambda.AddPermissionInput{
+		StatementId: aws.String(sid),
+		Action:      aws.String("lambda:InvokeFunction"),
+		Principal:   aws.String(principal),
+	}
+	if sourceArn != "" {
+		input.SourceArn = aws.String(sourceArn)
+	}
+	return input
+}
+
+func TestPermissionSignature(t *testing.T) {
+	base := permission("s3", "s3.amazonaws.com", "arn:aws:s3:::uploads")
+	tests := []struct {
+		name  string
+		other *lambda.AddPermissionInput
+		same  bool
+	}{
+		{"identical", permission("s3", "s3.amazonaws.com", "arn:aws:s3:::uploads"), true},
+		{"statement id and function are not part of the grant", &lambda.AddPermissionInput{
+			StatementId: aws.String("other"), FunctionName: aws.String("x"),
+			Action: aws.String("lambda:InvokeFunction"), Principal: aws.String("s3.amazonaws.com"), SourceArn: aws.String("arn:aws:s3:::uploads"),
+		}, true},
+		{"other principal", permission("s3", "sns.amazonaws.com", "arn:aws:s3:::uploads"), false},
+		{"other source arn", permission("s3", "s3.amazonaws.com", "arn:aws:s3:::other"), false},
+		{"no source arn", permission("s3", "s3.amazonaws.com", ""), false},
+		{"source account added", func() *lambda.AddPermissionInput {
+			p := permission("s3", "s3.amazonaws.com", "arn:aws:s3:::uploads")
+			p.SourceAccount = aws.String("123456789012")
+			return p
+		}(), false},
+		{"url auth type added", func() *lambda.AddPermissionInput {
+			p := permission("s3", "s3.amazonaws.com", "arn:aws:s3:::uploads")
+			p.FunctionUrlAuthType = types.FunctionUrlAuthTypeNone
+			return p
+		}(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := permissionSignature(base) == permissionSignature(tt.other); got != tt.same {
+				t.Errorf("same grant = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestPermissionChanges(t *testing.T) {
+	desired := map[string]*lambda.AddPermissionInput{
+		"|kept":         permission("kept", "s3.amazonaws.com", "arn:aws:s3:::uploads"),
+		"|changed":      permission("changed", "sns.amazonaws.com", "arn:aws:sns:us-east-1:123456789012:new"),
+		"|new":          permission("new", "events.amazonaws.com", ""),
+		"live|on-alias": permission("on-alias", "*", ""),
+	}
+	existing := map[string]*lambda.AddPermissionInput{
+		"|kept":    permission("kept", "s3.amazonaws.com", "arn:aws:s3:::uploads"),
+		"|changed": permission("changed", "sns.amazonaws.com", "arn:aws:sns:us-east-1:123456789012:old"),
+		"|gone":    permission("gone", "s3.amazonaws.com", ""),
+		//a statement with the same id on another qualifier is a different statement
+		"|on-alias": permission("on-alias", "*", ""),
+		//a statement the clone could not have written never matches
+		"|foreign": {StatementId: aws.String("foreign")},
+	}
+
+	add, replace, remove := permissionChanges(desired, existing)
+	if !reflect.DeepEqual(add, []string{"live|on-alias", "|new"}) {
+		t.Errorf("add = %v", add)
+	}
+	if !reflect.DeepEqual(replace, []string{"|changed"}) {
+		t.Errorf("replace = %v", replace)
+	}
+	if !reflect.DeepEqual(remove, []string{"|foreign", "|gone", "|on-alias"}) {
+		t.Errorf("remove = %v", remove)
+	}
+
+	add, replace, remove = permissionChanges(desired, desired)
+	if len(add)+len(replace)+len(remove) != 0 {
+		t.Errorf("an up to date clone got changes: add %v replace %v remove %v", add, replace, remove)
+	}
+}
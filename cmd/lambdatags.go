@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const (
+	tagClonedFrom = "cloned-from"
+	tagClonedAt   = "cloned-at"
+	tagClonedBy   = "cloned-by"
+)
+
+// tagRewriteRule matches "key=value" or any value of "key", a To of "key" renames and keeps the value
+type tagRewriteRule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// parseTagRewrites reads "env=prod => env=staging; owner => team"
+func parseTagRewrites(value string) []tagRewriteRule {
+	var rules []tagRewriteRule
+	for _, rule := range strings.Split(value, ";") {
+		from, to, found := strings.Cut(rule, "=>")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if found && from != "" && to != "" && !strings.HasPrefix(from, "=") && !strings.HasPrefix(to, "=") {
+			rules = append(rules, tagRewriteRule{From: from, To: to})
+		}
+	}
+	return rules
+}
+
+func formatTagRewrites(rules []tagRewriteRule) string {
+	var parts []string
+	for _, rule := range rules {
+		parts = append(parts, rule.From+" => "+rule.To)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (app *applicationMain) rewriteTags(tags map[string]string) {
+	for _, rule := range app.TagRewrites {
+		fromKey, fromValue, anyValue := strings.Cut(rule.From, "=")
+		anyValue = !anyValue
+		toKey, toValue, keepValue := strings.Cut(rule.To, "=")
+		keepValue = !keepValue
+
+		value, ok := tags[fromKey]
+		if !ok || (!anyValue && value != fromValue) {
+			continue
+		}
+		delete(tags, fromKey)
+		if keepValue {
+			toValue = value
+		}
+		tags[toKey] = toValue
+	}
+}
+
+func (j *cloneJob) cloneTags(sourceTags map[string]string) map[string]string {
+	tags := map[string]string{}
+	for k, v := range sourceTags {
+		tags[k] = v
+	}
+	for _, key := range j.app.TagDropKeys {
+		delete(tags, key)
+	}
+	j.app.rewriteTags(tags)
+	for k, v := range j.app.ExtraTags {
+		tags[k] = v
+	}
+
+	if j.app.ProvenanceTags {
+		tags[tagClonedFrom] = aws.ToString(j.source.Configuration.FunctionArn)
+		tags[tagClonedAt] = time.Now().UTC().Format(time.RFC3339)
+		tags[tagClonedBy] = j.clonedBy()
+	}
+	return tags
+}
+
+// clonedBy is the Target Role identity when one is set
+func (j *cloneJob) clonedBy() string {
+	if j.callerArn != "" {
+		return j.callerArn
+	}
+	j.callerArn = "unknown"
+	cfg, err := j.app.targetAwsConfig(j.ctx, j.targetRegion)
+	if err == nil {
+		if identity, err := callerIdentity(j.ctx, cfg); err == nil {
+			j.callerArn = aws.ToString(identity.Arn)
+		}
+	}
+	if j.callerArn == "unknown" {
+		j.report.warn("caller identity could not be read, %s tag set to unknown", tagClonedBy)
+	}
+	return j.callerArn
+}
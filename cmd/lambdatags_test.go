@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTagRewrites(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []tagRewriteRule
+	}{
+		{"empty", "", nil},
+		{"one rule", "env=prod => env=staging", []tagRewriteRule{{From: "env=prod", To: "env=staging"}}},
+		{"several rules with spacing", " env=prod=>env=staging ;owner => team ", []tagRewriteRule{{From: "env=prod", To: "env=staging"}, {From: "owner", To: "team"}}},
+		{"invalid rules are dropped", "env; => team; owner =>; =prod => env; env => =x; a => b", []tagRewriteRule{{From: "a", To: "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTagRewrites(tt.value)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseTagRewrites(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+			if back := parseTagRewrites(formatTagRewrites(got)); !reflect.DeepEqual(back, got) {
+				t.Errorf("round trip through formatTagRewrites = %v, want %v", back, got)
+			}
+		})
+	}
+}
+
+func TestRewriteTags(t *testing.T) {
+	source := map[string]string{"env": "prod", "owner": "alice", "team": "orders"}
+	tests := []struct {
+		name  string
+		rules string
+		want  map[string]string
+	}{
+		{"no rules", "", source},
+		{"pair replaces a matching value", "env=prod => env=staging", map[string]string{"env": "staging", "owner": "alice", "team": "orders"}},
+		{"pair leaves another value alone", "env=dev => env=staging", source},
+		{"bare key matches any value", "env => env=staging", map[string]string{"env": "staging", "owner": "alice", "team": "orders"}},
+		{"rename keeps the value", "owner => maintainer", map[string]string{"env": "prod", "maintainer": "alice", "team": "orders"}},
+		{"rename with a new value", "owner=alice => maintainer=platform", map[string]string{"env": "prod", "maintainer": "platform", "team": "orders"}},
+		{"missing key is ignored", "cost => center", source},
+		{"rules apply in order", "env=prod => stage=staging; stage => env", map[string]string{"env": "staging", "owner": "alice", "team": "orders"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := map[string]string{}
+			for k, v := range source {
+				tags[k] = v
+			}
+			app := applicationMain{TagRewrites: parseTagRewrites(tt.rules)}
+			app.rewriteTags(tags)
+			if !reflect.DeepEqual(tags, tt.want) {
+				t.Errorf("rewriteTags = %v, want %v", tags, tt.want)
+			}
+		})
+	}
+}
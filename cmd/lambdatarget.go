@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+func (app *applicationMain) targetRegion() string {
+	if app.TargetRegion != "" {
+		return app.TargetRegion
+	}
+	return app.Region
+}
+
+func (app *applicationMain) targetAwsConfig(ctx context.Context, region string) (aws.Config, error) {
+	cfg, err := app.awsConfig(ctx, region)
+	if err != nil || app.TargetRoleArn == "" {
+		return cfg, err
+	}
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), app.TargetRoleArn, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = "awscontrol-clone"
+		if app.TargetExternalId != "" {
+			o.ExternalID = aws.String(app.TargetExternalId)
+		}
+	})
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+	return cfg, nil
+}
+
+func callerIdentity(ctx context.Context, cfg aws.Config) (*sts.GetCallerIdentityOutput, error) {
+	return sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+}
+
+func callerAccount(ctx context.Context, cfg aws.Config) (string, error) {
+	identity, err := callerIdentity(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(identity.Account), nil
+}
+
+func (j *cloneJob) resolveAccounts() error {
+	if j.app.TargetRoleArn == "" {
+		return nil
+	}
+	sourceCfg, err := j.app.awsConfig(j.ctx, j.sourceRegion)
+	if err != nil {
+		return err
+	}
+	if j.sourceAccount, err = callerAccount(j.ctx, sourceCfg); err != nil {
+		return fmt.Errorf("failed to identify the source account:\n%v", err)
+	}
+	targetCfg, err := j.app.targetAwsConfig(j.ctx, j.targetRegion)
+	if err != nil {
+		return err
+	}
+	if j.targetAccount, err = callerAccount(j.ctx, targetCfg); err != nil {
+		return fmt.Errorf("failed to assume %s:\n%v", j.app.TargetRoleArn, err)
+	}
+	return nil
+}
+
+func (app *applicationMain) createTargetLambdaClient() (*lambda.Client, error) {
+	cfg, err := app.targetAwsConfig(context.Background(), app.targetRegion())
+	if err != nil {
+		return nil, err
+	}
+	return lambda.NewFromConfig(cfg), nil
+}
+
+// parseRewrites reads "old=new, old2=new2"
+func parseRewrites(value string) map[string]string {
+	rewrites := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		from, to, found := strings.Cut(pair, "=")
+		if found && strings.TrimSpace(from) != "" {
+			rewrites[strings.TrimSpace(from)] = strings.TrimSpace(to)
+		}
+	}
+	return rewrites
+}
+
+func formatRewrites(rewrites map[string]string) string {
+	var pairs []string
+	for from, to := range rewrites {
+		pairs = append(pairs, from+"="+to)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func (j *cloneJob) crossRegion() bool {
+	return j.targetRegion != j.sourceRegion
+}
+
+func (j *cloneJob) crossAccount() bool {
+	return j.targetAccount != j.sourceAccount
+}
+
+func (j *cloneJob) targetLabel() string {
+	if j.crossAccount() {
+		return fmt.Sprintf("account %s %s", j.targetAccount, j.targetRegion)
+	}
+	return j.targetRegion
+}
+
+func (j *cloneJob) rewritten(kind string, value string) (string, bool) {
+	to, ok := j.app.ResourceRewrites[value]
+	if ok && !j.translated[kind+value] {
+		j.translated[kind+value] = true
+		j.report.note("%s %s rewritten to %s", kind, value, to)
+	}
+	return to, ok
+}
+
+func (j *cloneJob) untranslated(kind string, value string) (string, bool) {
+	if !j.translated[kind+value] {
+		j.translated[kind+value] = true
+		j.report.warn("%s %s could not be translated to %s and was left out, add it to Resource Rewrites", kind, value, j.targetLabel())
+	}
+	return "", false
+}
+
+func (j *cloneJob) translateId(kind string, id string) (string, bool) {
+	if to, ok := j.rewritten(kind, id); ok {
+		return to, true
+	}
+	if !j.crossRegion() && !j.crossAccount() {
+		return id, true
+	}
+	return j.untranslated(kind, id)
+}
+
+func (j *cloneJob) translateIds(kind string, ids []string) ([]string, bool) {
+	var out []string
+	allOk := true
+	for _, id := range ids {
+		to, ok := j.translateId(kind, id)
+		if !ok {
+			allOk = false
+			continue
+		}
+		out = append(out, to)
+	}
+	return out, allOk
+}
+
+// translateArn moves an arn of the source region or account by name, rewrite rules win. KMS keys
+// (other than multi-region keys) and DynamoDB streams cannot be moved that way.
+func (j *cloneJob) translateArn(kind string, arn string) (string, bool) {
+	if to, ok := j.rewritten(kind, arn); ok {
+		return to, true
+	}
+	if !j.crossRegion() && !j.crossAccount() {
+		return arn, true
+	}
+
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" {
+		return arn, true
+	}
+	//global resources keep their region (none), resources of other accounts stay as they are
+	regionMoves := j.crossRegion() && parts[3] == j.sourceRegion
+	accountMoves := j.crossAccount() && parts[4] == j.sourceAccount
+	if !regionMoves && !accountMoves {
+		return arn, true
+	}
+	switch {
+	case parts[2] == "kms" && (accountMoves || !strings.HasPrefix(parts[5], "key/mrk-")):
+		return j.untranslated(kind, arn)
+	case parts[2] == "dynamodb" && strings.Contains(parts[5], "/stream/"):
+		return j.untranslated(kind, arn)
+	}
+
+	if regionMoves {
+		parts[3] = j.targetRegion
+	}
+	if accountMoves {
+		parts[4] = j.targetAccount
+	}
+	to := strings.Join(parts, ":")
+	if !j.translated[kind+arn] {
+		j.translated[kind+arn] = true
+		j.report.warn("%s moved to %s, make sure it exists there: %s", kind, j.targetLabel(), to)
+	}
+	return to, true
+}
+
+func (j *cloneJob) translateAccount(kind string, value string) string {
+	if to, ok := j.rewritten(kind, value); ok {
+		return to
+	}
+	if strings.HasPrefix(value, "arn:") {
+		to, _ := j.translateArn(kind, value)
+		return to
+	}
+	if j.crossAccount() && value == j.sourceAccount {
+		return j.targetAccount
+	}
+	return value
+}
+
+func (app *applicationMain) regionRewriteSummary() string {
+	if app.targetRegion() == app.Region && app.TargetRoleArn == "" {
+		return ""
+	}
+	summary := fmt.Sprintf("Target Region: %s (from %s)", app.targetRegion(), app.Region)
+	if app.TargetRoleArn != "" {
+		summary += "\nTarget Role: " + app.TargetRoleArn
+	}
+	if len(app.ResourceRewrites) > 0 {
+		summary += "\nResource Rewrites: " + formatRewrites(app.ResourceRewrites)
+	}
+	return summary
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+const latestVersion = "$LATEST"
+
+func arnQualifier(arn string) string {
+	if parts := strings.Split(arn, ":"); len(parts) == 8 {
+		return parts[7]
+	}
+	return ""
+}
+
+func pointsAtFunction(arn string, functionName string) bool {
+	parts := strings.Split(arn, ":")
+	return len(parts) >= 7 && parts[2] == "lambda" && parts[5] == "function" && parts[6] == functionName
+}
+
+// syncCloneLatest skips the upload when cloneSha already matches the source code
+func (j *cloneJob) syncCloneLatest(source *lambda.GetFunctionOutput, cloneSha string) (string, error) {
+	sourceSha := aws.ToString(source.Configuration.CodeSha256)
+	if sourceSha != cloneSha {
+		code, err := j.cloneCode(source)
+		if err != nil {
+			return cloneSha, err
+		}
+		_, err = j.dst.UpdateFunctionCode(j.ctx, updateCodeInput(j.newName, code))
+		if err != nil {
+			return cloneSha, fmt.Errorf("failed to update code of new Lambda function: %v", err)
+		}
+		if err = j.app.waitForFunctionUpdate(j.ctx, j.dst, j.newName); err != nil {
+			return sourceSha, err
+		}
+	}
+
+	_, err := j.dst.UpdateFunctionConfiguration(j.ctx, configUpdateInput(j.newFunctionInput(source.Configuration)))
+	if err != nil {
+		return sourceSha, fmt.Errorf("failed to update configuration of new Lambda function: %v", err)
+	}
+	return sourceSha, j.app.waitForFunctionUpdate(j.ctx, j.dst, j.newName)
+}
+
+func (j *cloneJob) publishCloneVersion(version string, cloneSha string) (string, string, error) {
+	source, err := j.src.GetFunction(j.ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(j.functionName),
+		Qualifier:    aws.String(version),
+	})
+	if err != nil {
+		return "", cloneSha, fmt.Errorf("failed to get version %s of %s:\n%v", version, j.functionName, err)
+	}
+
+	cloneSha, err = j.syncCloneLatest(source, cloneSha)
+	if err != nil {
+		return "", cloneSha, err
+	}
+
+	published, err := j.dst.PublishVersion(j.ctx, &lambda.PublishVersionInput{
+		FunctionName: aws.String(j.newName),
+		Description:  source.Configuration.Description,
+	})
+	if err != nil {
+		return "", cloneSha, fmt.Errorf("failed to publish version %s on new Lambda function: %v", version, err)
+	}
+	return aws.ToString(published.Version), cloneSha, nil
+}
+
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(a, b int) bool {
+		va, _ := strconv.Atoi(versions[a])
+		vb, _ := strconv.Atoi(versions[b])
+		return va < vb
+	})
+}
+
+func listFunctionVersions(ctx context.Context, clientLamb *lambda.Client, functionName string) ([]string, error) {
+	var versions []string
+	paginator := lambda.NewListVersionsByFunctionPaginator(clientLamb, &lambda.ListVersionsByFunctionInput{
+		FunctionName: aws.String(functionName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions: %v", err)
+		}
+		for _, v := range page.Versions {
+			if aws.ToString(v.Version) != latestVersion {
+				versions = append(versions, aws.ToString(v.Version))
+			}
+		}
+	}
+	return versions, nil
+}
+
+func (j *cloneJob) aliasesToClone() ([]types.AliasConfiguration, []string, error) {
+	ctx := j.ctx
+	var aliases []types.AliasConfiguration
+	paginator := lambda.NewListAliasesPaginator(j.src, &lambda.ListAliasesInput{
+		FunctionName: aws.String(j.functionName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list aliases: %v", err)
+		}
+		aliases = append(aliases, page.Aliases...)
+	}
+
+	//every version an alias points at, including the weighted secondary versions
+	needed := map[string]bool{}
+	for _, alias := range aliases {
+		needed[aws.ToString(alias.FunctionVersion)] = true
+		if alias.RoutingConfig != nil {
+			for version := range alias.RoutingConfig.AdditionalVersionWeights {
+				needed[version] = true
+			}
+		}
+	}
+	delete(needed, latestVersion)
+	if j.app.CloneVersionHistory {
+		all, err := listFunctionVersions(ctx, j.src, j.functionName)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, version := range all {
+			needed[version] = true
+		}
+	}
+	var versions []string
+	for version := range needed {
+		versions = append(versions, version)
+	}
+	sortVersions(versions)
+	return aliases, versions, nil
+}
+
+// aliasInput refuses weights that collapse onto one clone version, PublishVersion hands back the
+// existing version for identical code and configuration
+func (j *cloneJob) aliasInput(alias types.AliasConfiguration) (*lambda.CreateAliasInput, error) {
+	primary := aws.ToString(alias.FunctionVersion)
+	input := &lambda.CreateAliasInput{
+		FunctionName:    aws.String(j.newName),
+		Name:            alias.Name,
+		FunctionVersion: aws.String(j.qualifiers[primary]),
+		Description:     alias.Description,
+	}
+	if alias.RoutingConfig == nil || len(alias.RoutingConfig.AdditionalVersionWeights) == 0 {
+		return input, nil
+	}
+
+	routing := &types.AliasRoutingConfiguration{AdditionalVersionWeights: map[string]float64{}}
+	from := map[string]string{j.qualifiers[primary]: primary}
+	for version, weight := range alias.RoutingConfig.AdditionalVersionWeights {
+		cloneVersion := j.qualifiers[version]
+		if other, ok := from[cloneVersion]; ok {
+			return nil, fmt.Errorf("alias %s routes to source versions %s and %s, which are identical and both became clone version %s, so its weights cannot be recreated",
+				aws.ToString(alias.Name), other, version, cloneVersion)
+		}
+		from[cloneVersion] = version
+		routing.AdditionalVersionWeights[cloneVersion] = weight
+	}
+	input.RoutingConfig = routing
+	return input, nil
+}
+
+func (j *cloneJob) cloneAliases() error {
+	ctx := j.ctx
+	qualifiers := j.qualifiers
+	qualifiers[latestVersion] = latestVersion
+
+	aliases, versions, err := j.aliasesToClone()
+	if err != nil {
+		return err
+	}
+	if len(aliases) == 0 && len(versions) == 0 {
+		return nil
+	}
+
+	cloneSha := aws.ToString(j.source.Configuration.CodeSha256)
+	for _, version := range versions {
+		newVersion, sha, err := j.publishCloneVersion(version, cloneSha)
+		cloneSha = sha
+		if err != nil {
+			return err
+		}
+		for other, otherVersion := range qualifiers {
+			if otherVersion == newVersion && other != latestVersion {
+				j.report.warn("source versions %s and %s are identical and share clone version %s", other, version, newVersion)
+			}
+		}
+		qualifiers[version] = newVersion
+		j.report.note("version %s -> clone version %s", version, newVersion)
+	}
+
+	//put $LATEST back to the source's $LATEST after publishing older versions
+	if len(versions) > 0 {
+		if _, err := j.syncCloneLatest(j.source, cloneSha); err != nil {
+			return err
+		}
+	}
+
+	for _, alias := range aliases {
+		input, err := j.aliasInput(alias)
+		if err != nil {
+			return err
+		}
+		_, err = j.dst.CreateAlias(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to create alias on new Lambda function: %v", err)
+		}
+		j.createdAlias(aws.ToString(alias.Name))
+		qualifiers[aws.ToString(alias.Name)] = aws.ToString(alias.Name)
+		j.report.note("alias %s -> clone version %s", aws.ToString(alias.Name), qualifiers[aws.ToString(alias.FunctionVersion)])
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func TestAliasInput(t *testing.T) {
+	weighted := func(primary string, weights map[string]float64) types.AliasConfiguration {
+		return types.AliasConfiguration{
+			Name:            aws.String("live"),
+			FunctionVersion: aws.String(primary),
+			RoutingConfig:   &types.AliasRoutingConfiguration{AdditionalVersionWeights: weights},
+		}
+	}
+	tests := []struct {
+		name        string
+		qualifiers  map[string]string
+		alias       types.AliasConfiguration
+		wantVersion string
+		wantWeights map[string]float64
+		wantErr     bool
+	}{
+		{"plain", map[string]string{"3": "1"}, types.AliasConfiguration{Name: aws.String("live"), FunctionVersion: aws.String("3")}, "1", nil, false},
+		{"weighted", map[string]string{"3": "1", "4": "2"}, weighted("3", map[string]float64{"4": 0.1}), "1", map[string]float64{"2": 0.1}, false},
+		{"weight on the primary clone version", map[string]string{"3": "1", "4": "1"}, weighted("3", map[string]float64{"4": 0.1}), "", nil, true},
+		{"two weights on one clone version", map[string]string{"3": "1", "4": "2", "5": "2"}, weighted("3", map[string]float64{"4": 0.1, "5": 0.2}), "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &cloneJob{newName: "orders-staging", qualifiers: tt.qualifiers}
+			input, err := j.aliasInput(tt.alias)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := aws.ToString(input.FunctionVersion); got != tt.wantVersion {
+				t.Errorf("version = %s, want %s", got, tt.wantVersion)
+			}
+			var weights map[string]float64
+			if input.RoutingConfig != nil {
+				weights = input.RoutingConfig.AdditionalVersionWeights
+			}
+			if len(weights) != len(tt.wantWeights) {
+				t.Fatalf("weights = %v, want %v", weights, tt.wantWeights)
+			}
+			for k, v := range tt.wantWeights {
+				if weights[k] != v {
+					t.Errorf("weights = %v, want %v", weights, tt.wantWeights)
+				}
+			}
+		})
+	}
+}
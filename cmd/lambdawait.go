@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+const defaultWaitSeconds = 300
+
+func (app *applicationMain) activeWait() time.Duration {
+	if app.ActiveWaitSeconds > 0 {
+		return time.Duration(app.ActiveWaitSeconds) * time.Second
+	}
+	return defaultWaitSeconds * time.Second
+}
+
+func (app *applicationMain) updateWait() time.Duration {
+	if app.UpdateWaitSeconds > 0 {
+		return time.Duration(app.UpdateWaitSeconds) * time.Second
+	}
+	return defaultWaitSeconds * time.Second
+}
+
+func parseWaitSeconds(value string) int {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return seconds
+}
+
+func (app *applicationMain) waitForFunctionActive(ctx context.Context, clientLamb *lambda.Client, functionName string) error {
+	waiter := lambda.NewFunctionActiveV2Waiter(clientLamb)
+	err := waiter.Wait(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(functionName)}, app.activeWait())
+	if err == nil {
+		return nil
+	}
+	cfg, getErr := clientLamb.GetFunctionConfiguration(ctx, &lambda.GetFunctionConfigurationInput{FunctionName: aws.String(functionName)})
+	if getErr != nil {
+		return fmt.Errorf("function %s did not become active:\n%v", functionName, err)
+	}
+	return fmt.Errorf("function %s did not become active (%s, %s): %s", functionName, cfg.State, cfg.StateReasonCode, aws.ToString(cfg.StateReason))
+}
+
+func (app *applicationMain) waitForFunctionUpdate(ctx context.Context, clientLamb *lambda.Client, functionName string) error {
+	waiter := lambda.NewFunctionUpdatedV2Waiter(clientLamb)
+	err := waiter.Wait(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(functionName)}, app.updateWait())
+	if err == nil {
+		return nil
+	}
+	cfg, getErr := clientLamb.GetFunctionConfiguration(ctx, &lambda.GetFunctionConfigurationInput{FunctionName: aws.String(functionName)})
+	if getErr != nil {
+		return fmt.Errorf("update of function %s did not finish:\n%v", functionName, err)
+	}
+	return fmt.Errorf("update of function %s did not finish (%s, %s): %s", functionName, cfg.LastUpdateStatus, cfg.LastUpdateStatusReasonCode, aws.ToString(cfg.LastUpdateStatusReason))
+}
@@ -30,12 +30,43 @@ var (
 )
 
 type applicationMain struct {
-	AwsKey            string `json:"awskey"`
-	AwsSecret         string `json:"awssecret"`
-	Region            string `json:"region"`
-	SessionToken      string `json:"session"`
-	FileNameExtension string `json:"filenameextension"`
-	ReplaceExtension  string `json:"replaceextension"`
+	AwsKey                  string            `json:"awskey"`
+	AwsSecret               string            `json:"awssecret"`
+	Region                  string            `json:"region"`
+	SessionToken            string            `json:"session"`
+	FileNameExtension       string            `json:"filenameextension"`
+	ReplaceExtension        string            `json:"replaceextension"`
+	ImageRepository         string            `json:"imagerepository"`
+	CloneVersionHistory     bool              `json:"cloneversionhistory"`
+	TargetRegion            string            `json:"targetregion"`
+	ResourceRewrites        map[string]string `json:"resourcerewrites"`
+	TargetRoleArn           string            `json:"targetrolearn"`
+	TargetExternalId        string            `json:"targetexternalid"`
+	KeepPartialClone        bool              `json:"keeppartialclone"`
+	SyncExisting            bool              `json:"syncexisting"`
+	StagingBucket           string            `json:"stagingbucket"`
+	RuntimePins             map[string]string `json:"runtimepins"`
+	DeprecationWindowDays   int               `json:"deprecationwindowdays"`
+	CanaryAlias             string            `json:"canaryalias"`
+	CanarySteps             []int             `json:"canarysteps"`
+	CanaryWaitSeconds       int               `json:"canarywaitseconds"`
+	CanaryPayload           string            `json:"canarypayload"`
+	CanaryErrorRate         float64           `json:"canaryerrorrate"`
+	AllowIncompatibleLayers bool              `json:"allowincompatiblelayers"`
+	SmokeTest               bool              `json:"smoketest"`
+	PayloadDirectory        string            `json:"payloaddirectory"`
+	NameTemplate            string            `json:"nametemplate"`
+	NamePattern             string            `json:"namepattern"`
+	ProvenanceTags          bool              `json:"provenancetags"`
+	ExtraTags               map[string]string `json:"extratags"`
+	TagDropKeys             []string          `json:"tagdropkeys"`
+	TagRewrites             []tagRewriteRule  `json:"tagrewriterules"`
+	EnvDropKeys             []string          `json:"envdropkeys"`
+	EnvReplace              map[string]string `json:"envreplace"`
+	EnvRegexRewrites        []envRegexRule    `json:"envregexrewrites"`
+	EnvOverrides            map[string]string `json:"envoverrides"`
+	ActiveWaitSeconds       int               `json:"activewaitseconds"`
+	UpdateWaitSeconds       int               `json:"updatewaitseconds"`
 }
 
 func main() {
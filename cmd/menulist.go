@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,6 +33,8 @@ var (
 	menuColorLambda     = "214"
 	menuColorMain       = "170"
 	menuColorGlue       = "51"
+	optionOnColor       = "112"
+	optionOffColor      = "244"
 
 	menuTOP = []string{
 		"Enter AWS Key",
@@ -52,6 +55,98 @@ var (
 		"Clone Lambda",
 		"Upgrade Lambda",
 		"Clone + Upgrade Lambda",
+		"Runtime Deprecation Report",
+		"Revert From Journal",
+		"Clone Options",
+	}
+
+	cloneOptions = []cloneOption{
+		{name: "Clone Version History", flag: func(app *applicationMain) *bool { return &app.CloneVersionHistory }},
+		{name: "Sync Existing Clones", flag: func(app *applicationMain) *bool { return &app.SyncExisting }},
+		{name: "Provenance Tags", flag: func(app *applicationMain) *bool { return &app.ProvenanceTags }},
+		{name: "Keep Partial Clone On Failure", flag: func(app *applicationMain) *bool { return &app.KeepPartialClone }},
+		{name: "Allow Incompatible Layers", flag: func(app *applicationMain) *bool { return &app.AllowIncompatibleLayers }},
+		{name: "Smoke Test After Upgrade/Clone", flag: func(app *applicationMain) *bool { return &app.SmokeTest }},
+		{name: "Payload Directory", placeholder: "saved test events, <function>.json and <function>.expected.json, default payloads",
+			get: func(app *applicationMain) string { return app.payloadDirectory() },
+			set: func(app *applicationMain, v string) { app.PayloadDirectory = v }},
+		{name: "Runtime Pins", placeholder: "upgrade targets by family or function, e.g., python=python3.12, orders-api=nodejs20.x",
+			get: func(app *applicationMain) string { return formatRewrites(app.RuntimePins) },
+			set: func(app *applicationMain, v string) { app.RuntimePins = parseRewrites(v) }},
+		{name: "Name Template", placeholder: "e.g., {{.Name}}-staging or {{.Group 1}}-{{.Runtime}}-{{.Date}} (blank uses New Text)",
+			get: func(app *applicationMain) string { return app.NameTemplate },
+			set: func(app *applicationMain, v string) { app.NameTemplate = v }},
+		{name: "Name Pattern", placeholder: "regex on the source name for {{.Group n}}, e.g., ^(.*)-prod$",
+			get: func(app *applicationMain) string { return app.NamePattern },
+			set: func(app *applicationMain, v string) { app.NamePattern = v }},
+		{name: "Image Repository", placeholder: "e.g., 123456789012.dkr.ecr.us-west-2.amazonaws.com/my-repo",
+			get: func(app *applicationMain) string { return app.ImageRepository },
+			set: func(app *applicationMain, v string) { app.ImageRepository = v }},
+		{name: "Staging Bucket", placeholder: "S3 bucket in the target region for code packages over 50 MB",
+			get: func(app *applicationMain) string { return app.StagingBucket },
+			set: func(app *applicationMain, v string) { app.StagingBucket = v }},
+		{name: "Target Region", placeholder: "e.g., us-west-2 (blank clones into the current region)",
+			get: func(app *applicationMain) string { return app.TargetRegion },
+			set: func(app *applicationMain, v string) { app.TargetRegion = v }},
+		{name: "Target Role ARN", placeholder: "e.g., arn:aws:iam::210987654321:role/clone-target (blank uses your own keys)",
+			get: func(app *applicationMain) string { return app.TargetRoleArn },
+			set: func(app *applicationMain, v string) { app.TargetRoleArn = v }},
+		{name: "Target External ID", placeholder: "external id required by the target role, if any",
+			get: func(app *applicationMain) string { return app.TargetExternalId },
+			set: func(app *applicationMain, v string) { app.TargetExternalId = v }},
+		{name: "Extra Tags", placeholder: "e.g., team=payments, cost-center=1234",
+			get: func(app *applicationMain) string { return formatRewrites(app.ExtraTags) },
+			set: func(app *applicationMain, v string) { app.ExtraTags = parseRewrites(v) }},
+		{name: "Tag Drop Keys", placeholder: "e.g., backup-plan, pager",
+			get: func(app *applicationMain) string { return strings.Join(app.TagDropKeys, ", ") },
+			set: func(app *applicationMain, v string) { app.TagDropKeys = parseEnvKeys(v) }},
+		{name: "Tag Rewrites", placeholder: "key=value or key, separated by ;, e.g., env=prod => env=staging; owner => team",
+			get: func(app *applicationMain) string { return formatTagRewrites(app.TagRewrites) },
+			set: func(app *applicationMain, v string) { app.TagRewrites = parseTagRewrites(v) }},
+		{name: "Env Drop Keys", placeholder: "e.g., DEBUG_TOKEN, PROD_ONLY_FLAG",
+			get: func(app *applicationMain) string { return strings.Join(app.EnvDropKeys, ", ") },
+			set: func(app *applicationMain, v string) { app.EnvDropKeys = parseEnvKeys(v) }},
+		{name: "Env Replace Text", placeholder: "e.g., orders-prod=orders-staging, prod.example.com=staging.example.com",
+			get: func(app *applicationMain) string { return formatRewrites(app.EnvReplace) },
+			set: func(app *applicationMain, v string) { app.EnvReplace = parseRewrites(v) }},
+		{name: "Env Regex Rewrites", placeholder: "e.g., ^(.*)-prod$ => ${1}-staging; :5432/prod => :5432/staging",
+			get: func(app *applicationMain) string { return formatEnvRegexRules(app.EnvRegexRewrites) },
+			set: func(app *applicationMain, v string) { app.EnvRegexRewrites = parseEnvRegexRules(v) }},
+		{name: "Env Overrides", placeholder: "e.g., STAGE=staging, LOG_LEVEL=debug",
+			get: func(app *applicationMain) string { return formatRewrites(app.EnvOverrides) },
+			set: func(app *applicationMain, v string) { app.EnvOverrides = parseRewrites(v) }},
+		{name: "Canary Alias", placeholder: "alias an upgrade shifts over step by step, e.g., live (blank upgrades $LATEST in place)",
+			get: func(app *applicationMain) string { return app.CanaryAlias },
+			set: func(app *applicationMain, v string) { app.CanaryAlias = v }},
+		{name: "Canary Steps (%)", placeholder: "share of alias traffic per step before promotion, default 10, 50",
+			get: func(app *applicationMain) string { return formatCanarySteps(app.canarySteps()) },
+			set: func(app *applicationMain, v string) { app.CanarySteps = parseCanarySteps(v) }},
+		{name: "Canary Step Wait (s)", placeholder: "seconds each step runs before its health check, default 120",
+			get: func(app *applicationMain) string { return strconv.Itoa(int(app.canaryWait().Seconds())) },
+			set: func(app *applicationMain, v string) { app.CanaryWaitSeconds = parseWaitSeconds(v) }},
+		{name: "Canary Smoke Payload", placeholder: "JSON event invoked on the new version after each step, e.g., {\"ping\": true}",
+			get: func(app *applicationMain) string { return app.CanaryPayload },
+			set: func(app *applicationMain, v string) { app.CanaryPayload = v }},
+		{name: "Canary Max Error Rate (%)", placeholder: "highest error rate of the new version a step may show, e.g., 1 (blank turns it off)",
+			get: func(app *applicationMain) string {
+				if app.CanaryErrorRate == 0 {
+					return ""
+				}
+				return strconv.FormatFloat(app.CanaryErrorRate, 'f', -1, 64)
+			},
+			set: func(app *applicationMain, v string) { app.CanaryErrorRate = parseErrorRate(v) }},
+		{name: "Deprecation Window (days)", placeholder: "days ahead a deprecation shows as approaching, default 180",
+			get: func(app *applicationMain) string { return strconv.Itoa(app.deprecationWindow()) },
+			set: func(app *applicationMain, v string) { app.DeprecationWindowDays = parseDays(v) }},
+		{name: "Active Wait Timeout (s)", placeholder: "seconds a new function may stay Pending, default 300",
+			get: func(app *applicationMain) string { return strconv.Itoa(int(app.activeWait().Seconds())) },
+			set: func(app *applicationMain, v string) { app.ActiveWaitSeconds = parseWaitSeconds(v) }},
+		{name: "Update Wait Timeout (s)", placeholder: "seconds a code or config update may take, default 300",
+			get: func(app *applicationMain) string { return strconv.Itoa(int(app.updateWait().Seconds())) },
+			set: func(app *applicationMain, v string) { app.UpdateWaitSeconds = parseWaitSeconds(v) }},
+		{name: "Resource Rewrites", placeholder: "e.g., subnet-0abc=subnet-0def, sg-0123=sg-0456",
+			get: func(app *applicationMain) string { return formatRewrites(app.ResourceRewrites) },
+			set: func(app *applicationMain, v string) { app.ResourceRewrites = parseRewrites(v) }},
 	}
 
 	menuGLUE = []string{
@@ -59,6 +154,29 @@ var (
 	}
 )
 
+// cloneOption is an on/off switch (flag) or a typed value (get/set) on the Clone Options menu
+type cloneOption struct {
+	name        string
+	flag        func(app *applicationMain) *bool
+	get         func(app *applicationMain) string
+	set         func(app *applicationMain, value string)
+	placeholder string
+}
+
+func (o cloneOption) display(app *applicationMain) string {
+	if o.get != nil {
+		value := o.get(app)
+		if value == "" {
+			value = "(not set)"
+		}
+		return fmt.Sprintf("%s: %s", o.name, lipgloss.NewStyle().Foreground(lipgloss.Color(optionOnColor)).Render(value))
+	}
+	if *o.flag(app) {
+		return fmt.Sprintf("%s: %s", o.name, lipgloss.NewStyle().Foreground(lipgloss.Color(optionOnColor)).Render("ON"))
+	}
+	return fmt.Sprintf("%s: %s", o.name, lipgloss.NewStyle().Foreground(lipgloss.Color(optionOffColor)).Render("OFF"))
+}
+
 // MENU STRUCTURE
 type itemX struct {
 	name        string
@@ -84,7 +202,7 @@ func (d itemDelegateX) Render(w io.Writer, lm list.Model, index int, listItem li
 	fn := itemStyle.Render
 
 	switch d.currentState {
-	case StateMenuMAIN, StateMenuLAMBDA, StateMenuGLUE:
+	case StateMenuMAIN, StateMenuLAMBDA, StateMenuGLUE, StateMenuCLONEOPTS:
 		str := fmt.Sprintf("%d. %s", index+1, i.displayName)
 		if index == lm.Index() {
 			fn = func(s ...string) string {
@@ -92,7 +210,12 @@ func (d itemDelegateX) Render(w io.Writer, lm list.Model, index int, listItem li
 			}
 		}
 		fmt.Fprint(w, fn(str))
-	case StateLambdaClone, StateLambdaUpgrade, StateLambdaDubba:
+	case StateLambdaClone, StateLambdaUpgrade, StateLambdaDubba, StateLambdaDeprecation:
+		//phase headings on the deprecation report cannot be selected
+		if i.name == "" {
+			fmt.Fprint(w, lipgloss.NewStyle().Foreground(lipgloss.Color(menuColorLambda)).Bold(true).Render(i.displayName))
+			return
+		}
 		checkbox := "[ ]"
 		if i.selected {
 			checkbox = "[x]"
@@ -106,6 +229,13 @@ func (d itemDelegateX) Render(w io.Writer, lm list.Model, index int, listItem li
 
 	case StateLambdaList:
 		fmt.Fprint(w, i.displayName)
+
+	case StateLambdaRevert:
+		cursor := "  "
+		if index == lm.Index() {
+			cursor = "> "
+		}
+		fmt.Fprint(w, cursor+i.displayName)
 	}
 }
 
@@ -124,6 +254,9 @@ const (
 	StateMenuLAMBDA
 	StateMenuGLUE
 	StateLambdaDubba
+	StateMenuCLONEOPTS
+	StateLambdaDeprecation
+	StateLambdaRevert
 )
 
 type OutroDisplayState int
@@ -132,12 +265,42 @@ const (
 	OutroEsc OutroDisplayState = iota
 	OutroEnterClone
 	OutroEnterUpdate
+	OutroPlan
+	OutroEnterRevert
 )
 
 type backgroundJobMsg struct {
 	result string
 }
 
+type planJobMsg struct {
+	plan string
+}
+
+type progressMsg struct {
+	line     string
+	progress chan string
+}
+
+// waitForProgress returns nothing once the job closes the channel
+func waitForProgress(progress chan string) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-progress
+		if !ok {
+			return nil
+		}
+		return progressMsg{line: line, progress: progress}
+	}
+}
+
+type scanJobMsg struct {
+	result string
+}
+
+type clonePreviewMsg struct {
+	result string
+}
+
 type JobList int
 
 type MenuList struct {
@@ -157,6 +320,13 @@ type MenuList struct {
 	app                 *applicationMain
 	stateOutroDisplay   OutroDisplayState
 	lambdaSelectedList  []string
+	lambdaNewNames      map[string]string
+	lambdaRuntimes      map[string]string
+	mappingsDisabled    bool
+	plan                string
+	deprecationReport   string
+	journalEntries      []journalEntry
+	revertEntry         journalEntry
 }
 
 func (m MenuList) Init() tea.Cmd {
@@ -171,12 +341,18 @@ func (m MenuList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateMenuLambda(msg)
 	case StateMenuGLUE:
 		return m.updateMenuGlue(msg)
+	case StateMenuCLONEOPTS:
+		return m.updateMenuCloneOptions(msg)
 	case StateLambdaList:
 		return m.updateLambdaList(msg)
 	case StateLambdaClone, StateLambdaDubba:
 		return m.updateLambdaClone(msg)
 	case StateLambdaUpgrade:
 		return m.updateLambdaUpgrade(msg)
+	case StateLambdaDeprecation:
+		return m.updateLambdaDeprecation(msg)
+	case StateLambdaRevert:
+		return m.updateLambdaRevert(msg)
 	case StateSpinner:
 		return m.updateSpinner(msg)
 	case StateTextInput:
@@ -265,8 +441,30 @@ func (m *MenuList) updateLambdaClone(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				if len(selectedItems) > 0 {
 					m.lambdaSelectedList = selectedItems
-					m.backgroundJobResult = strings.Join(selectedItems, "\n")
+					m.mappingsDisabled = false
+					names, preview, namesOk := m.app.cloneNames(selectedItems, m.lambdaRuntimes, m.state == StateLambdaDubba)
+					m.lambdaNewNames = names
+					m.backgroundJobResult = preview
 					m.prevState = m.state
+					if !namesOk {
+						//nothing runs until the names are fixed
+						m.backgroundJobResult += "\n\nFix the Name Template, Name Pattern or New Text before cloning."
+						m.textInputError = true
+						m.stateOutroDisplay = OutroEsc
+						m.state = StateResultDisplay
+						break
+					}
+					if m.state == StateLambdaDubba {
+						m.backgroundJobResult += "\n\nRuntime upgrades:\n" + m.app.upgradePreview(selectedItems, m.lambdaRuntimes)
+					}
+					if summary := m.app.regionRewriteSummary(); summary != "" {
+						m.backgroundJobResult += "\n\n" + summary
+					}
+					if m.app.hasEnvRewrites() {
+						//the environment preview reads every selected function
+						m.state = StateSpinner
+						return m, tea.Batch(m.spinner.Tick, m.backgroundEnvPreview(m.backgroundJobResult))
+					}
 					m.stateOutroDisplay = OutroEnterClone
 					m.state = StateResultDisplay
 				}
@@ -321,10 +519,9 @@ func (m *MenuList) updateLambdaUpgrade(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				if len(selectedItems) > 0 {
 					m.lambdaSelectedList = selectedItems
-					m.backgroundJobResult = strings.Join(selectedItems, "\n")
 					m.prevState = m.state
-					m.stateOutroDisplay = OutroEnterClone
-					m.state = StateResultDisplay
+					m.state = StateSpinner
+					return m, tea.Batch(m.spinner.Tick, m.backgroundScanUpgrade())
 				}
 			}
 
@@ -336,6 +533,112 @@ func (m *MenuList) updateLambdaUpgrade(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// updateLambdaDeprecation sends the selected functions straight to the Upgrade confirmation
+func (m *MenuList) updateLambdaDeprecation(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		switch msg.String() {
+		case "esc":
+			if m.list.FilterState() == list.FilterApplied {
+				m.list.ResetFilter()
+				m.list.Title = "Runtime Deprecation Report"
+				m.list.Styles.Title = lipTitleStyle
+				return m, nil
+			}
+			m.prevState = m.state
+			m.state = StateMenuLAMBDA
+			m.fillListItems()
+			return m, nil
+
+		case " ":
+			i, ok := m.list.SelectedItem().(*itemX)
+			if ok && i.name != "" {
+				for idx, val := range m.list.Items() {
+					item := val.(*itemX)
+					if item.name == i.name {
+						item.selected = !item.selected
+						m.list.SetItem(idx, item)
+					}
+				}
+			}
+		case "e":
+			fileName, err := exportText("deprecation", m.deprecationReport)
+			if err != nil {
+				m.list.Title = err.Error()
+			} else {
+				m.list.Title = "Runtime Deprecation Report exported to " + fileName
+			}
+			return m, nil
+		case "enter":
+			selectedItems := []string{}
+			for _, it := range m.list.Items() {
+				i := it.(*itemX)
+				if i.selected {
+					selectedItems = append(selectedItems, i.name)
+				}
+			}
+			if len(selectedItems) > 0 {
+				m.lambdaSelectedList = selectedItems
+				//from here on it is the Upgrade flow
+				m.prevState = StateLambdaUpgrade
+				m.state = StateSpinner
+				return m, tea.Batch(m.spinner.Tick, m.backgroundScanUpgrade())
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m *MenuList) updateLambdaRevert(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		switch msg.String() {
+		case "esc":
+			if m.list.FilterState() == list.FilterApplied {
+				m.list.ResetFilter()
+				return m, nil
+			}
+			m.prevState = m.state
+			m.state = StateMenuLAMBDA
+			m.fillListItems()
+			return m, nil
+		case "enter":
+			i, ok := m.list.SelectedItem().(*itemX)
+			if !ok {
+				return m, nil
+			}
+			idx, _ := strconv.Atoi(i.name)
+			m.revertEntry = m.journalEntries[idx]
+			m.prevState = m.state
+			m.state = StateResultDisplay
+			preview, err := m.app.revertPreview(m.revertEntry)
+			if err != nil {
+				m.backgroundJobResult = err.Error()
+				m.textInputError = true
+				m.stateOutroDisplay = OutroEsc
+				return m, nil
+			}
+			m.backgroundJobResult = preview
+			m.stateOutroDisplay = OutroEnterRevert
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
 func (m *MenuList) updateMenuMain(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -503,6 +806,21 @@ func (m *MenuList) updateMenuLambda(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.fillListItems()
 						return m, nil
 					}
+				case menuLAMBDA[4]:
+					m.prevState = m.state
+					m.state = StateLambdaDeprecation
+					m.fillListItems()
+					return m, nil
+				case menuLAMBDA[5]:
+					m.prevState = m.state
+					m.state = StateLambdaRevert
+					m.fillListItems()
+					return m, nil
+				case menuLAMBDA[6]:
+					m.prevState = m.state
+					m.state = StateMenuCLONEOPTS
+					m.fillListItems()
+					return m, nil
 				}
 			}
 			return m, nil
@@ -513,6 +831,45 @@ func (m *MenuList) updateMenuLambda(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+func (m *MenuList) updateMenuCloneOptions(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.state = StateMenuLAMBDA
+			m.fillListItems()
+			return m, nil
+		case "enter", " ":
+			idx := m.list.Index()
+			if idx < 0 || idx >= len(cloneOptions) {
+				return m, nil
+			}
+			option := cloneOptions[idx]
+			if option.get != nil {
+				m.prevState = m.state
+				m.state = StateTextInput
+				m.inputPrompt = option.name
+				m.textInput = textinput.New()
+				m.textInput.Placeholder = option.placeholder
+				m.textInput.SetValue(option.get(m.app))
+				m.textInput.Focus()
+				m.textInput.CharLimit = 1000
+				m.textInput.Width = 200
+				m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+				m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+				return m, nil
+			}
+			value := option.flag(m.app)
+			*value = !*value
+			m.list.SetItem(idx, &itemX{option.name, false, option.display(m.app)})
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
 func (m *MenuList) updateMenuGlue(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -561,6 +918,18 @@ func (m *MenuList) updateTextInput(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyEnter:
 			inputValue := m.textInput.Value() // User pressed enter, save the input
 
+			//clone options go straight back to their menu which shows the new value
+			if m.prevState == StateMenuCLONEOPTS {
+				for _, option := range cloneOptions {
+					if option.name == m.inputPrompt && option.set != nil {
+						option.set(m.app, strings.TrimSpace(inputValue))
+					}
+				}
+				m.state = StateMenuCLONEOPTS
+				m.fillListItems()
+				return m, nil
+			}
+
 			switch m.inputPrompt {
 			case menuTOP[0]:
 				m.app.AwsKey = inputValue
@@ -611,14 +980,43 @@ func (m *MenuList) updateSpinner(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.spinner, cmd = m.spinner.Update(msg)
 			return m, cmd
 		}
+	case progressMsg:
+		//the spinner screen keeps the latest lines in view
+		lines := append(strings.Split(m.jobOutcome, "\n"), msg.line)
+		if m.jobOutcome == "" {
+			lines = lines[1:]
+		}
+		if len(lines) > 15 {
+			lines = lines[len(lines)-15:]
+		}
+		m.jobOutcome = strings.Join(lines, "\n")
+		return m, waitForProgress(msg.progress)
 	case backgroundJobMsg:
 		m.backgroundJobResult = m.jobOutcome + "\n\n" + msg.result + "\n"
-		if m.prevState != StateLambdaClone && m.prevState != StateLambdaUpgrade && m.prevState != StateLambdaList {
+		m.jobOutcome = ""
+		if m.prevState != StateLambdaClone && m.prevState != StateLambdaUpgrade && m.prevState != StateLambdaList && m.prevState != StateLambdaRevert {
 			m.prevState = m.state
 		}
 		m.stateOutroDisplay = OutroEsc
 		m.state = StateResultDisplay
 		return m, nil
+	case scanJobMsg:
+		m.backgroundJobResult = msg.result
+		m.stateOutroDisplay = OutroEnterUpdate
+		m.state = StateResultDisplay
+		return m, nil
+	case clonePreviewMsg:
+		m.backgroundJobResult = msg.result
+		m.stateOutroDisplay = OutroEnterClone
+		m.state = StateResultDisplay
+		return m, nil
+	case planJobMsg:
+		//back to the confirmation screen, prevState still says which job to run
+		m.plan = msg.plan
+		m.backgroundJobResult = msg.plan
+		m.stateOutroDisplay = OutroPlan
+		m.state = StateResultDisplay
+		return m, nil
 	// case continueLambda:
 	// 	return m, tea.Batch(m.spinner.Tick, m.backgroundCloneLambda(m.lambdaFunction))
 	default:
@@ -634,10 +1032,11 @@ func (m *MenuList) updateResultDisplay(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case "q", "esc":
 			m.backgroundJobResult = ""
+			m.plan = ""
 			m.textInputError = false
 			//this requires special conditionals becuase ResultDisplay is used to show
 			//results but also for list selection
-			if m.prevState == StateLambdaClone || m.prevState == StateLambdaUpgrade || m.prevState == StateLambdaList {
+			if m.prevState == StateLambdaClone || m.prevState == StateLambdaUpgrade || m.prevState == StateLambdaList || m.prevState == StateLambdaRevert {
 				m.state = StateMenuLAMBDA
 			} else {
 				m.state = StateMenuMAIN
@@ -646,7 +1045,31 @@ func (m *MenuList) updateResultDisplay(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		case "ctrl+c":
 			return m, tea.Quit
+		case "p":
+			if m.stateOutroDisplay == OutroEnterClone || m.stateOutroDisplay == OutroEnterUpdate {
+				m.state = StateSpinner
+				return m, tea.Batch(m.spinner.Tick, m.backgroundPlan())
+			}
+		case "d":
+			//chosen per clone run, not saved with the settings
+			if m.stateOutroDisplay == OutroEnterClone {
+				m.mappingsDisabled = !m.mappingsDisabled
+			}
+		case "e":
+			if m.stateOutroDisplay == OutroPlan && m.plan != "" {
+				fileName, err := exportPlan(m.plan)
+				if err != nil {
+					m.backgroundJobResult = m.plan + "\n" + err.Error()
+				} else {
+					m.backgroundJobResult = m.plan + "\nPlan exported to " + fileName
+				}
+			}
 		case "enter":
+			//the result of a finished job is only dismissed
+			if m.stateOutroDisplay == OutroEsc {
+				return m, nil
+			}
+			m.plan = ""
 			switch m.prevState {
 			case StateLambdaClone:
 				m.state = StateSpinner
@@ -654,11 +1077,19 @@ func (m *MenuList) updateResultDisplay(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			case StateLambdaUpgrade:
 				m.state = StateSpinner
+				if m.app.CanaryAlias != "" {
+					progress := make(chan string)
+					return m, tea.Batch(m.spinner.Tick, m.backgroundCanaryLambda(progress), waitForProgress(progress))
+				}
 				return m, tea.Batch(m.spinner.Tick, m.backgroundUpdateLambda())
 
 			case StateLambdaDubba:
 				m.state = StateSpinner
 				return m, tea.Batch(m.spinner.Tick, m.backgroundCloneLambda(true))
+
+			case StateLambdaRevert:
+				m.state = StateSpinner
+				return m, tea.Batch(m.spinner.Tick, m.backgroundRevert())
 			}
 		}
 	}
@@ -672,9 +1103,17 @@ func (m MenuList) viewResultDisplay() string {
 	case OutroEsc:
 		outro = "Press 'esc' to return."
 	case OutroEnterClone:
-		outro = "Press 'enter' to Clone these Lambda functions"
+		mappings := "as on the source"
+		if m.mappingsDisabled {
+			mappings = "disabled"
+		}
+		outro = fmt.Sprintf("Event source mappings are created %s, press 'd' to switch.\nPress 'enter' to Clone these Lambda functions or 'p' to see the plan", mappings)
 	case OutroEnterUpdate:
-		outro = "Press 'enter' to Upgrade these Lambda functions"
+		outro = "Press 'enter' to Upgrade these Lambda functions or 'p' to see the plan"
+	case OutroPlan:
+		outro = "Press 'enter' to run this plan, 'e' to export it or 'esc' to return."
+	case OutroEnterRevert:
+		outro = "Press 'enter' to Revert this change or 'esc' to return."
 	}
 
 	outroRender := lipgloss.NewStyle().Foreground(lipgloss.Color("231")).Bold(true).Render(outro)
@@ -689,10 +1128,10 @@ func (m MenuList) viewResultDisplay() string {
 
 func (m MenuList) View() string {
 	switch m.state {
-	case StateMenuMAIN, StateMenuLAMBDA, StateMenuGLUE:
+	case StateMenuMAIN, StateMenuLAMBDA, StateMenuGLUE, StateMenuCLONEOPTS:
 		m.header = m.app.getHeader()
 		return m.header + "\n" + m.list.View()
-	case StateLambdaClone, StateLambdaUpgrade, StateLambdaList, StateLambdaDubba:
+	case StateLambdaClone, StateLambdaUpgrade, StateLambdaList, StateLambdaDubba, StateLambdaDeprecation, StateLambdaRevert:
 		return m.list.View()
 	case StateSpinner:
 		return m.viewSpinner()
@@ -744,6 +1183,13 @@ func (m *MenuList) fillListItems() {
 		}
 		m.list.SetItems(items)
 
+	case StateMenuCLONEOPTS:
+		items := []list.Item{}
+		for _, option := range cloneOptions {
+			items = append(items, &itemX{option.name, false, option.display(m.app)})
+		}
+		m.list.SetItems(items)
+
 	case StateLambdaClone, StateLambdaUpgrade, StateLambdaList, StateLambdaDubba:
 		lambdas, err := m.app.listAllLambdaFunctions()
 		if err != nil {
@@ -753,10 +1199,68 @@ func (m *MenuList) fillListItems() {
 			break
 		}
 		items := []list.Item{}
+		m.lambdaRuntimes = map[string]string{}
+		for _, value := range lambdas {
+			m.lambdaRuntimes[value[0]] = value[1]
+			runtime := value[1]
+			//the upgrade lists show where each function would go
+			if m.state == StateLambdaUpgrade || m.state == StateLambdaDubba {
+				runtime = m.app.upgradeLabel(value[0], value[1])
+			} else if phase := m.app.runtimePhaseLabel(value[1]); phase != "" {
+				runtime += " (" + strings.ToLower(phase) + ")"
+			}
+			items = append(items, &itemX{value[0], false, fmt.Sprintf("%s   %s", value[0], lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(runtime))})
+		}
+
+		m.list.SetItems(items)
+
+	case StateLambdaDeprecation:
+		lambdas, err := m.app.listAllLambdaFunctions()
+		if err != nil {
+			m.backgroundJobResult = err.Error()
+			m.stateOutroDisplay = OutroEsc
+			m.state = StateResultDisplay
+			break
+		}
+		m.lambdaRuntimes = map[string]string{}
 		for _, value := range lambdas {
-			items = append(items, &itemX{value[0], false, fmt.Sprintf("%s   %s", value[0], lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(value[1]))})
+			m.lambdaRuntimes[value[0]] = value[1]
 		}
+		now := time.Now()
+		functions := m.app.deprecationReport(lambdas, now)
+		m.deprecationReport = m.app.formatDeprecationReport(functions, now)
 
+		//one heading per phase followed by its functions
+		items := []list.Item{}
+		phase := phaseSupported
+		for _, f := range functions {
+			if f.phase != phase {
+				phase = f.phase
+				items = append(items, &itemX{"", false, fmt.Sprintf("%s (%d)", phaseLabels[phase], countPhase(functions, phase))})
+			}
+			items = append(items, &itemX{f.name, false, fmt.Sprintf("%s   %s", f.name, lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(f.runtime+", "+f.describe(now)))})
+		}
+		if len(items) == 0 {
+			m.list.Title = "Runtime Deprecation Report (no functions on deprecated runtimes)"
+		}
+		m.list.SetItems(items)
+
+	case StateLambdaRevert:
+		entries, err := loadJournal()
+		if err != nil {
+			m.backgroundJobResult = err.Error()
+			m.stateOutroDisplay = OutroEsc
+			m.state = StateResultDisplay
+			break
+		}
+		m.journalEntries = entries
+		items := []list.Item{}
+		for idx, entry := range entries {
+			items = append(items, &itemX{strconv.Itoa(idx), false, entry.label()})
+		}
+		if len(items) == 0 {
+			m.list.Title = "Revert From Journal (nothing journaled yet)"
+		}
 		m.list.SetItems(items)
 
 	}
@@ -779,25 +1283,61 @@ func (m *MenuList) backgroundCloneLambda(upgrade2 bool) tea.Cmd {
 		m.spinner.Style = lipgloss.NewStyle().Foreground(lipgloss.Color(spinnerColor)) //white = 231
 		m.spinnerMsg = "Cloning Lambda"
 		resultX := "The Lamb is Cloned"
+		var reports []string
 
-		var newNameX string
 		for _, v := range m.lambdaSelectedList {
-			if m.app.ReplaceExtension != "" {
-				if strings.Contains(v, m.app.ReplaceExtension) {
-					newNameX = strings.Replace(v, m.app.ReplaceExtension, m.app.FileNameExtension, -1)
-				} else {
-					newNameX = fmt.Sprintf("%s%s", v, m.app.FileNameExtension)
-				}
-			} else {
-				newNameX = fmt.Sprintf("%s%s", v, m.app.FileNameExtension)
+			report, err := m.app.cloneLambda(v, m.lambdaNewNames[v], upgrade2, m.mappingsDisabled)
+			if err != nil {
+				resultX = "Clone finished with errors"
+				report.warn("%v", err)
+			}
+			reports = append(reports, report.String())
+		}
+		return backgroundJobMsg{result: resultX + "\n\n" + strings.Join(reports, "\n")}
+	}
+}
+
+func (m *MenuList) backgroundPlan() tea.Cmd {
+	return func() tea.Msg {
+		m.spinner.Style = lipgloss.NewStyle().Foreground(lipgloss.Color(spinnerColor)) //white = 231
+		m.spinnerMsg = "Planning"
+		var reports []string
+
+		for _, v := range m.lambdaSelectedList {
+			var report *lambdaReport
+			var err error
+			switch m.prevState {
+			case StateLambdaUpgrade:
+				report, err = m.app.planUpgrade(v)
+			default:
+				report, err = m.app.planClone(v, m.lambdaNewNames[v], m.prevState == StateLambdaDubba, m.mappingsDisabled)
 			}
-			err := m.app.cloneLambda(v, newNameX, upgrade2)
 			if err != nil {
-				resultX = err.Error()
-				continue
+				report.warn("%v", err)
 			}
+			reports = append(reports, report.String())
+		}
+		plan := strings.Join(reports, "\n")
+		if summary := m.app.regionRewriteSummary(); summary != "" && m.prevState != StateLambdaUpgrade {
+			plan += "\n" + summary + "\n"
 		}
-		return backgroundJobMsg{result: resultX}
+		return planJobMsg{plan: plan}
+	}
+}
+
+func (m *MenuList) backgroundEnvPreview(preview string) tea.Cmd {
+	return func() tea.Msg {
+		m.spinner.Style = lipgloss.NewStyle().Foreground(lipgloss.Color(spinnerColor)) //white = 231
+		m.spinnerMsg = "Reading environments"
+		return clonePreviewMsg{result: preview + "\n\n" + m.app.envRewriteSummary(m.lambdaSelectedList)}
+	}
+}
+
+func (m *MenuList) backgroundScanUpgrade() tea.Cmd {
+	return func() tea.Msg {
+		m.spinner.Style = lipgloss.NewStyle().Foreground(lipgloss.Color(spinnerColor)) //white = 231
+		m.spinnerMsg = "Scanning code for the new runtime"
+		return scanJobMsg{result: m.app.upgradeScanPreview(m.lambdaSelectedList)}
 	}
 }
 
@@ -806,15 +1346,51 @@ func (m *MenuList) backgroundUpdateLambda() tea.Cmd {
 		m.spinner.Style = lipgloss.NewStyle().Foreground(lipgloss.Color(spinnerColor)) //white = 231
 		m.spinnerMsg = "Upgrading Lambda Runtime"
 		resultX := "The Lamb is Upgraded"
+		var reports []string
+
+		for _, v := range m.lambdaSelectedList {
+			report, err := m.app.upgradeLambda(v)
+			if err != nil {
+				resultX = "Upgrade finished with errors"
+				report.warn("%v", err)
+			}
+			reports = append(reports, report.String())
+		}
+		return backgroundJobMsg{result: resultX + "\n\n" + strings.Join(reports, "\n")}
+	}
+}
+
+func (m *MenuList) backgroundCanaryLambda(progress chan string) tea.Cmd {
+	return func() tea.Msg {
+		defer close(progress)
+		m.spinner.Style = lipgloss.NewStyle().Foreground(lipgloss.Color(spinnerColor)) //white = 231
+		m.spinnerMsg = "Canary Upgrading Lambda Runtime"
+		resultX := "The Lamb is Upgraded"
+		var reports []string
 
 		for _, v := range m.lambdaSelectedList {
-			err := m.app.upgradeLambda(v)
+			report, err := m.app.canaryUpgrade(v, func(line string) { progress <- line })
 			if err != nil {
-				resultX = err.Error()
-				continue
+				resultX = "Upgrade finished with errors"
+				report.warn("%v", err)
 			}
+			reports = append(reports, report.String())
 		}
-		return backgroundJobMsg{result: resultX}
+		return backgroundJobMsg{result: resultX + "\n\n" + strings.Join(reports, "\n")}
+	}
+}
+
+func (m *MenuList) backgroundRevert() tea.Cmd {
+	return func() tea.Msg {
+		m.spinner.Style = lipgloss.NewStyle().Foreground(lipgloss.Color(spinnerColor)) //white = 231
+		m.spinnerMsg = "Reverting Lambda"
+		resultX := "The Lamb is Reverted"
+		report, err := m.app.revertJournalEntry(m.revertEntry)
+		if err != nil {
+			resultX = "Revert finished with errors"
+			report.warn("%v", err)
+		}
+		return backgroundJobMsg{result: resultX + "\n\n" + report.String()}
 	}
 }
 
@@ -838,6 +1414,10 @@ func SetupListMenu(currentState MenuState) list.Model {
 		lm.SetFilteringEnabled(false)
 		lm.SetShowTitle(false)
 		selectedItemStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color(menuColorGlue))
+	case StateMenuCLONEOPTS:
+		lm.SetFilteringEnabled(false)
+		lm.SetShowTitle(false)
+		selectedItemStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color(menuColorLambda))
 	case StateLambdaClone:
 		lm.SetHeight(27)
 		lm.SetFilteringEnabled(true)
@@ -858,6 +1438,16 @@ func SetupListMenu(currentState MenuState) list.Model {
 		lm.SetFilteringEnabled(true)
 		lm.SetShowTitle(true)
 		lm.Title = "Clone + Upgrade Lambda Functions"
+	case StateLambdaDeprecation:
+		lm.SetHeight(27)
+		lm.SetFilteringEnabled(true)
+		lm.SetShowTitle(true)
+		lm.Title = "Runtime Deprecation Report"
+	case StateLambdaRevert:
+		lm.SetHeight(27)
+		lm.SetFilteringEnabled(true)
+		lm.SetShowTitle(true)
+		lm.Title = "Revert From Journal"
 	}
 
 	lm.Styles.Title = lipTitleStyle
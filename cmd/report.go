@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type lambdaReport struct {
+	title    string
+	notes    []string
+	warnings []string
+}
+
+func newLambdaReport(title string) *lambdaReport {
+	return &lambdaReport{title: title}
+}
+
+func (r *lambdaReport) note(format string, a ...any) {
+	r.notes = append(r.notes, fmt.Sprintf(format, a...))
+}
+
+// warn records a warning once, steps that run per version would otherwise repeat it
+func (r *lambdaReport) warn(format string, a ...any) {
+	w := fmt.Sprintf(format, a...)
+	for _, existing := range r.warnings {
+		if existing == w {
+			return
+		}
+	}
+	r.warnings = append(r.warnings, w)
+}
+
+func (r *lambdaReport) String() string {
+	var sb strings.Builder
+	sb.WriteString(r.title + "\n")
+	for _, n := range r.notes {
+		sb.WriteString("   " + n + "\n")
+	}
+	if len(r.warnings) > 0 {
+		sb.WriteString("   Warnings:\n")
+		for _, w := range r.warnings {
+			sb.WriteString("   ! " + w + "\n")
+		}
+	}
+	return sb.String()
+}
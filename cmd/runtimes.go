@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// runtimeFamily lists the runtimes of one language from oldest to newest
+type runtimeFamily struct {
+	name     string
+	runtimes []types.Runtime
+}
+
+var runtimeCatalog = []runtimeFamily{
+	{name: "nodejs", runtimes: []types.Runtime{
+		types.RuntimeNodejs, types.RuntimeNodejs43, types.RuntimeNodejs43edge, types.RuntimeNodejs610, types.RuntimeNodejs810,
+		types.RuntimeNodejs10x, types.RuntimeNodejs12x, types.RuntimeNodejs14x, types.RuntimeNodejs16x, types.RuntimeNodejs18x,
+		types.RuntimeNodejs20x, types.RuntimeNodejs22x,
+	}},
+	{name: "python", runtimes: []types.Runtime{
+		types.RuntimePython27, types.RuntimePython36, types.RuntimePython37, types.RuntimePython38, types.RuntimePython39,
+		types.RuntimePython310, types.RuntimePython311, types.RuntimePython312, types.RuntimePython313,
+	}},
+	{name: "java", runtimes: []types.Runtime{
+		types.RuntimeJava8, types.RuntimeJava8al2, types.RuntimeJava11, types.RuntimeJava17, types.RuntimeJava21,
+	}},
+	{name: "dotnet", runtimes: []types.Runtime{
+		types.RuntimeDotnetcore10, types.RuntimeDotnetcore20, types.RuntimeDotnetcore21, types.RuntimeDotnetcore31,
+		types.RuntimeDotnet6, types.RuntimeDotnet8,
+	}},
+	{name: "ruby", runtimes: []types.Runtime{
+		types.RuntimeRuby25, types.RuntimeRuby27, types.RuntimeRuby32, types.RuntimeRuby33,
+	}},
+	{name: "provided", runtimes: []types.Runtime{
+		types.RuntimeProvided, types.RuntimeProvidedal2, types.RuntimeProvidedal2023,
+	}},
+}
+
+type runtimeSchedule struct {
+	deprecated  time.Time
+	blockCreate time.Time
+	blockUpdate time.Time
+}
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// runtimeSchedules are the dates AWS publishes on the Lambda runtimes page
+var runtimeSchedules = map[types.Runtime]runtimeSchedule{
+	types.RuntimeNodejs:         {day(2016, 10, 31), day(2016, 10, 31), day(2016, 10, 31)},
+	types.RuntimeNodejs43:       {day(2020, 3, 5), day(2020, 3, 5), day(2020, 3, 5)},
+	types.RuntimeNodejs43edge:   {day(2020, 3, 5), day(2020, 3, 5), day(2020, 3, 5)},
+	types.RuntimeNodejs610:      {day(2019, 8, 12), day(2019, 8, 12), day(2019, 8, 12)},
+	types.RuntimeNodejs810:      {day(2020, 3, 6), day(2020, 3, 6), day(2020, 3, 6)},
+	types.RuntimeNodejs10x:      {day(2021, 7, 30), day(2021, 7, 30), day(2022, 2, 14)},
+	types.RuntimeNodejs12x:      {day(2023, 3, 31), day(2023, 3, 31), day(2023, 4, 30)},
+	types.RuntimeNodejs14x:      {day(2023, 12, 4), day(2024, 1, 9), day(2025, 2, 28)},
+	types.RuntimeNodejs16x:      {day(2024, 6, 12), day(2025, 2, 28), day(2025, 3, 31)},
+	types.RuntimeNodejs18x:      {day(2025, 9, 1), day(2026, 2, 3), day(2026, 3, 9)},
+	types.RuntimeNodejs20x:      {day(2026, 4, 30), day(2026, 6, 1), day(2026, 7, 1)},
+	types.RuntimeNodejs22x:      {day(2027, 4, 30), day(2027, 6, 1), day(2027, 7, 1)},
+	types.RuntimePython27:       {day(2021, 7, 15), day(2021, 7, 15), day(2022, 5, 30)},
+	types.RuntimePython36:       {day(2022, 7, 18), day(2022, 7, 18), day(2022, 8, 29)},
+	types.RuntimePython37:       {day(2023, 12, 4), day(2024, 1, 9), day(2025, 2, 28)},
+	types.RuntimePython38:       {day(2024, 10, 14), day(2025, 2, 28), day(2025, 3, 31)},
+	types.RuntimePython39:       {day(2025, 12, 15), day(2026, 6, 1), day(2026, 7, 1)},
+	types.RuntimePython310:      {day(2026, 6, 30), day(2026, 7, 31), day(2026, 8, 31)},
+	types.RuntimePython311:      {day(2027, 6, 30), day(2027, 7, 31), day(2027, 8, 31)},
+	types.RuntimePython312:      {day(2028, 10, 31), day(2028, 11, 30), day(2029, 1, 10)},
+	types.RuntimePython313:      {day(2029, 6, 30), day(2029, 7, 31), day(2029, 8, 31)},
+	types.RuntimeJava8:          {day(2024, 1, 8), day(2024, 2, 8), day(2025, 2, 28)},
+	types.RuntimeJava8al2:       {day(2026, 6, 30), day(2026, 7, 31), day(2026, 8, 31)},
+	types.RuntimeJava11:         {day(2026, 6, 30), day(2026, 7, 31), day(2026, 8, 31)},
+	types.RuntimeJava17:         {day(2026, 6, 30), day(2026, 7, 31), day(2026, 8, 31)},
+	types.RuntimeJava21:         {day(2029, 6, 30), day(2029, 7, 31), day(2029, 8, 31)},
+	types.RuntimeDotnetcore10:   {day(2019, 7, 30), day(2019, 7, 30), day(2019, 7, 30)},
+	types.RuntimeDotnetcore20:   {day(2019, 5, 30), day(2019, 5, 30), day(2019, 5, 30)},
+	types.RuntimeDotnetcore21:   {day(2022, 1, 5), day(2022, 1, 5), day(2022, 4, 13)},
+	types.RuntimeDotnetcore31:   {day(2023, 4, 3), day(2023, 4, 3), day(2023, 5, 3)},
+	types.RuntimeDotnet6:        {day(2024, 12, 20), day(2025, 2, 28), day(2025, 3, 31)},
+	types.RuntimeDotnet8:        {day(2026, 11, 10), day(2026, 12, 10), day(2027, 1, 11)},
+	types.RuntimeRuby25:         {day(2021, 7, 30), day(2021, 7, 30), day(2022, 3, 31)},
+	types.RuntimeRuby27:         {day(2023, 12, 7), day(2024, 1, 9), day(2025, 2, 28)},
+	types.RuntimeRuby32:         {day(2026, 3, 31), day(2026, 4, 30), day(2026, 5, 31)},
+	types.RuntimeRuby33:         {day(2027, 3, 31), day(2027, 4, 30), day(2027, 5, 31)},
+	types.RuntimeGo1x:           {day(2024, 1, 8), day(2024, 2, 8), day(2025, 2, 28)},
+	types.RuntimeProvided:       {day(2024, 1, 8), day(2024, 2, 8), day(2025, 2, 28)},
+	types.RuntimeProvidedal2:    {day(2026, 6, 30), day(2026, 7, 31), day(2026, 8, 31)},
+	types.RuntimeProvidedal2023: {day(2029, 6, 30), day(2029, 7, 31), day(2029, 8, 31)},
+}
+
+func runtimeFamilyOf(runtime types.Runtime) (*runtimeFamily, int) {
+	for f := range runtimeCatalog {
+		for i, r := range runtimeCatalog[f].runtimes {
+			if r == runtime {
+				return &runtimeCatalog[f], i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// upgradeRuntime picks the newest of the family unless a function or family pin says otherwise
+func (app *applicationMain) upgradeRuntime(functionName string, current types.Runtime) (types.Runtime, error) {
+	family, position := runtimeFamilyOf(current)
+	if family == nil {
+		return current, fmt.Errorf("no upgrade path for runtime %s", current)
+	}
+
+	pin, pinned := app.RuntimePins[functionName]
+	if !pinned {
+		pin, pinned = app.RuntimePins[family.name]
+	}
+	if !pinned {
+		return family.runtimes[len(family.runtimes)-1], nil
+	}
+	pinFamily, pinPosition := runtimeFamilyOf(types.Runtime(pin))
+	switch {
+	case pinFamily != family:
+		return current, fmt.Errorf("runtime pin %s is not a %s runtime", pin, family.name)
+	case pinPosition < position:
+		return current, fmt.Errorf("runtime pin %s is older than %s", pin, current)
+	}
+	return types.Runtime(pin), nil
+}
+
+func (app *applicationMain) upgradeLabel(functionName string, current string) string {
+	if current == "" {
+		return "container image, no runtime to upgrade"
+	}
+	target, err := app.upgradeRuntime(functionName, types.Runtime(current))
+	switch {
+	case err != nil:
+		return fmt.Sprintf("%s (%v)", current, err)
+	case target == types.Runtime(current):
+		return current + " (already current)"
+	}
+	return fmt.Sprintf("%s -> %s", current, target)
+}
+
+func (app *applicationMain) upgradePreview(functionNames []string, runtimes map[string]string) string {
+	var lines []string
+	for _, name := range functionNames {
+		lines = append(lines, fmt.Sprintf("%s   %s", name, app.upgradeLabel(name, runtimes[name])))
+	}
+	return strings.Join(lines, "\n")
+}
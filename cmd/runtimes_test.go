@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func TestUpgradeRuntime(t *testing.T) {
+	tests := []struct {
+		name     string
+		pins     map[string]string
+		function string
+		current  types.Runtime
+		want     types.Runtime
+		wantErr  bool
+	}{
+		{"newest of the family", nil, "orders", types.RuntimePython39, types.RuntimePython313, false},
+		{"already newest", nil, "orders", types.RuntimeNodejs22x, types.RuntimeNodejs22x, false},
+		{"family pin", map[string]string{"python": "python3.12"}, "orders", types.RuntimePython39, types.RuntimePython312, false},
+		{"family pin of another family is ignored", map[string]string{"nodejs": "nodejs20.x"}, "orders", types.RuntimePython39, types.RuntimePython313, false},
+		{"function pin wins over family pin", map[string]string{"python": "python3.12", "orders": "python3.11"}, "orders", types.RuntimePython39, types.RuntimePython311, false},
+		{"function pin of another family", map[string]string{"orders": "nodejs22.x"}, "orders", types.RuntimePython39, types.RuntimePython39, true},
+		{"pin older than the current runtime", map[string]string{"python": "python3.9"}, "orders", types.RuntimePython311, types.RuntimePython311, true},
+		{"pin equal to the current runtime", map[string]string{"python": "python3.11"}, "orders", types.RuntimePython311, types.RuntimePython311, false},
+		{"unknown pin", map[string]string{"orders": "python4.0"}, "orders", types.RuntimePython39, types.RuntimePython39, true},
+		{"runtime without a family", nil, "orders", types.RuntimeGo1x, types.RuntimeGo1x, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := applicationMain{RuntimePins: tt.pins}
+			got, err := app.upgradeRuntime(tt.function, tt.current)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("upgradeRuntime error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("upgradeRuntime = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}